@@ -0,0 +1,98 @@
+package cmdlog
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+
+	"oss.terrastruct.com/util-go/xos"
+)
+
+const pkgPath = "oss.terrastruct.com/util-go/cmdlog"
+
+// GetCaller reports whether the file:line of the caller is appended to each entry.
+func (l *Logger) GetCaller() bool {
+	return l.co.enabled()
+}
+
+// SetCaller enables appending the file:line of the caller to each entry.
+// Caller locations are also enabled by $CMDLOG_CALLER.
+//
+// When enabled, errors logged with Trace are printed with their full frame chain.
+func (l *Logger) SetCaller(enabled bool) {
+	vi := int64(0)
+	if enabled {
+		vi = 1
+	}
+	atomic.StoreInt64(&l.co.flag, vi)
+}
+
+// Trace returns a value that formats err with all of its xerrors frames
+// like %+v when caller locations are enabled and like %v otherwise.
+// Use it to print where an error annotated with xdefer.Errorf came from.
+//
+//	l.Error.Printf("failed to render: %v", l.Trace(err))
+func (l *Logger) Trace(err error) fmt.Formatter {
+	return traceError{
+		err:    err,
+		detail: l.co.enabled(),
+	}
+}
+
+type traceError struct {
+	err    error
+	detail bool
+}
+
+func (te traceError) Format(f fmt.State, c rune) {
+	if te.detail {
+		fmt.Fprintf(f, "%+v", te.err)
+		return
+	}
+	fmt.Fprintf(f, "%v", te.err)
+}
+
+type callerOpt struct {
+	flag int64
+	env  *xos.Env
+}
+
+func (co *callerOpt) enabled() bool {
+	if atomic.LoadInt64(&co.flag) == 0 {
+		eb, _ := co.env.Bool("CMDLOG_CALLER")
+		return eb != nil && *eb
+	}
+	return true
+}
+
+// callerLocation returns the dir/file:line of the first frame outside of the log
+// and cmdlog packages.
+func callerLocation() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := frames.Next()
+		if !strings.HasPrefix(f.Function, "log.") && !strings.HasPrefix(f.Function, pkgPath+".") {
+			return fmt.Sprintf("%s/%s:%d", filepath.Base(filepath.Dir(f.File)), filepath.Base(f.File), f.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// appendNote appends (note) to the end of the entry p.
+func appendNote(p []byte, note string) []byte {
+	p = bytes.TrimSuffix(p, []byte("\n"))
+	p2 := make([]byte, 0, len(p)+len(note)+4)
+	p2 = append(p2, p...)
+	if len(p) > 0 {
+		p2 = append(p2, ' ')
+	}
+	p2 = append(p2, "("+note+")\n"...)
+	return p2
+}
@@ -0,0 +1,144 @@
+package cmdlog
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"oss.terrastruct.com/util-go/xos"
+)
+
+// Entry is a single log entry recorded by a Capture.
+type Entry struct {
+	Level    Level     `json:"level"`
+	Prefixes []string  `json:"prefixes"`
+	Time     time.Time `json:"time"`
+	Message  string    `json:"message"`
+
+	// Caller is the file:line that logged the entry when caller locations
+	// are enabled. See Logger.SetCaller.
+	Caller string `json:"caller,omitempty"`
+}
+
+// String formats e like a Logger would without color.
+func (e Entry) String() string {
+	var sb strings.Builder
+	sb.WriteString("[" + e.Time.Format(defaultTSFormat) + "]")
+	for _, p := range e.Prefixes {
+		sb.WriteString(" " + p + ":")
+	}
+	if e.Level != LevelNone {
+		sb.WriteString(" " + e.Level.String() + ":")
+	}
+	if e.Message != "" {
+		sb.WriteString(" " + e.Message)
+	}
+	return sb.String()
+}
+
+// Capture records every entry written to a Logger created with NewCapture.
+//
+// Use it in tests to assert that something was logged at a given level.
+type Capture struct {
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// NewCapture returns a Logger that records all entries into the returned Capture
+// instead of writing them out. Disabled debug entries are not recorded.
+//
+// If tb fails, all recorded entries are logged with tb.Logf on cleanup.
+func NewCapture(env *xos.Env, tb testing.TB) (*Logger, *Capture) {
+	c := &Capture{}
+	l := New(env, io.Discard)
+	l.c = c
+	l.init("")
+
+	tb.Cleanup(func() {
+		if tb.Failed() {
+			tb.Logf("cmdlog: captured entries:\n%s", c)
+		}
+	})
+	return l, c
+}
+
+func (c *Capture) record(lvl Level, prefixes []string, caller string, p []byte) {
+	e := Entry{
+		Level:    lvl,
+		Prefixes: append([]string(nil), prefixes...),
+		Time:     timeNow(),
+		Message:  strings.TrimSuffix(string(p), "\n"),
+		Caller:   caller,
+	}
+
+	c.mu.Lock()
+	c.entries = append(c.entries, e)
+	c.mu.Unlock()
+}
+
+// Entries returns all recorded entries in the order they were logged.
+func (c *Capture) Entries() []Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Entry(nil), c.entries...)
+}
+
+// Filter returns all recorded entries for which fn returns true.
+func (c *Capture) Filter(fn func(Entry) bool) []Entry {
+	var entries []Entry
+	for _, e := range c.Entries() {
+		if fn(e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Level returns all recorded entries logged at lvl.
+func (c *Capture) Level(lvl Level) []Entry {
+	return c.Filter(func(e Entry) bool {
+		return e.Level == lvl
+	})
+}
+
+// Contains reports whether an entry at lvl whose message contains substr was logged.
+func (c *Capture) Contains(lvl Level, substr string) bool {
+	return len(c.Filter(func(e Entry) bool {
+		return e.Level == lvl && strings.Contains(e.Message, substr)
+	})) > 0
+}
+
+// AssertContains fails tb if no entry at lvl containing substr was logged.
+func (c *Capture) AssertContains(tb testing.TB, lvl Level, substr string) {
+	tb.Helper()
+	if !c.Contains(lvl, substr) {
+		tb.Fatalf("expected %s entry containing %q", lvl, substr)
+	}
+}
+
+// AssertNotContains fails tb if an entry at lvl containing substr was logged.
+func (c *Capture) AssertNotContains(tb testing.TB, lvl Level, substr string) {
+	tb.Helper()
+	if c.Contains(lvl, substr) {
+		tb.Fatalf("unexpected %s entry containing %q", lvl, substr)
+	}
+}
+
+// Reset discards all recorded entries.
+func (c *Capture) Reset() {
+	c.mu.Lock()
+	c.entries = nil
+	c.mu.Unlock()
+}
+
+// String returns all recorded entries, one per line.
+func (c *Capture) String() string {
+	var sb strings.Builder
+	for _, e := range c.Entries() {
+		sb.WriteString(e.String())
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
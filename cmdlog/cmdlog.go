@@ -6,11 +6,13 @@ import (
 	"io"
 	"log"
 	"os"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
+	"oss.terrastruct.com/util-go/xdefer"
 	"oss.terrastruct.com/util-go/xos"
 	"oss.terrastruct.com/util-go/xterm"
 )
@@ -20,9 +22,9 @@ var timeNow = time.Now
 const defaultTSFormat = "15:04:05"
 
 func init() {
-	l := New(xos.NewEnv(os.Environ()), os.Stderr)
-	l.SetTS(true)
-	l = l.WithPrefix(xterm.Blue, "stdlog")
+	std = New(xos.NewEnv(os.Environ()), os.Stderr)
+	std.SetTS(true)
+	l := std.WithPrefix(xterm.Blue, "stdlog")
 
 	log.SetOutput(l.NoLevel.Writer())
 	log.SetPrefix(l.NoLevel.Prefix())
@@ -32,8 +34,16 @@ func init() {
 type Logger struct {
 	env *xos.Env
 	w   io.Writer
+	pgw *progressWriter
 	tsw *tsWriter
 	dw  *debugWriter
+	co  *callerOpt
+	smp *sampler
+	c   *Capture
+
+	// names is the chain of prefixes added with WithPrefix and WithCCPrefix
+	// without any formatting.
+	names []string
 
 	NoLevel *log.Logger
 	Debug   *log.Logger
@@ -43,6 +53,39 @@ type Logger struct {
 	Error   *log.Logger
 }
 
+// Level identifies which of a Logger's loggers an entry was written to.
+type Level int
+
+const (
+	LevelNone Level = iota
+	LevelDebug
+	LevelSuccess
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (lvl Level) String() string {
+	switch lvl {
+	case LevelDebug:
+		return "debug"
+	case LevelSuccess:
+		return "success"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "err"
+	default:
+		return "nolevel"
+	}
+}
+
+func (lvl Level) MarshalText() ([]byte, error) {
+	return []byte(lvl.String()), nil
+}
+
 func (l *Logger) GetTS() bool {
 	l.tsw.mu.Lock()
 	defer l.tsw.mu.Unlock()
@@ -80,12 +123,16 @@ func (l *Logger) SetDebug(enabled bool) {
 }
 
 func New(env *xos.Env, w io.Writer) *Logger {
-	tsw := &tsWriter{w: w, tsfmt: defaultTSFormat}
+	pgw := &progressWriter{w: w}
+	tsw := &tsWriter{w: pgw, tsfmt: defaultTSFormat}
 	dw := &debugWriter{w: tsw, env: env}
 	l := &Logger{
 		env: env,
 		w:   w,
+		pgw: pgw,
 		dw:  dw,
+		co:  &callerOpt{env: env},
+		smp: &sampler{},
 		tsw: tsw,
 	}
 	l.init("")
@@ -93,24 +140,60 @@ func New(env *xos.Env, w io.Writer) *Logger {
 }
 
 func (l *Logger) init(prefix string) {
-	l.NoLevel = log.New(prefixWriter{l.tsw, prefix}, "", 0)
+	l.NoLevel = log.New(l.prefixWriter(l.tsw, LevelNone, prefix), "", 0)
 
 	if prefix != "" {
 		prefix += " "
 	}
-	l.Debug = log.New(prefixWriter{l.dw, prefix + xterm.Prefix(l.env, l.w, "", "debug")}, "", 0)
-	l.Success = log.New(prefixWriter{l.tsw, prefix + xterm.Prefix(l.env, l.w, xterm.Green, "success")}, "", 0)
-	l.Info = log.New(prefixWriter{l.tsw, prefix + xterm.Prefix(l.env, l.w, xterm.Blue, "info")}, "", 0)
-	l.Warn = log.New(prefixWriter{l.tsw, prefix + xterm.Prefix(l.env, l.w, xterm.Yellow, "warn")}, "", 0)
-	l.Error = log.New(prefixWriter{l.tsw, prefix + xterm.Prefix(l.env, l.w, xterm.Red, "err")}, "", 0)
+	l.Debug = log.New(l.prefixWriter(l.dw, LevelDebug, prefix+xterm.Prefix(l.env, l.w, "", "debug")), "", 0)
+	l.Success = log.New(l.prefixWriter(l.tsw, LevelSuccess, prefix+xterm.Prefix(l.env, l.w, xterm.Green, "success")), "", 0)
+	l.Info = log.New(l.prefixWriter(l.tsw, LevelInfo, prefix+xterm.Prefix(l.env, l.w, xterm.Blue, "info")), "", 0)
+	l.Warn = log.New(l.prefixWriter(l.tsw, LevelWarn, prefix+xterm.Prefix(l.env, l.w, xterm.Yellow, "warn")), "", 0)
+	l.Error = log.New(l.prefixWriter(l.tsw, LevelError, prefix+xterm.Prefix(l.env, l.w, xterm.Red, "err")), "", 0)
+}
+
+func (l *Logger) prefixWriter(w io.Writer, lvl Level, prefix string) prefixWriter {
+	return prefixWriter{
+		w:      w,
+		prefix: prefix,
+		level:  lvl,
+		l:      l,
+	}
 }
 
 type prefixWriter struct {
 	w      io.Writer
 	prefix string
+
+	level Level
+	l     *Logger
 }
 
 func (pw prefixWriter) Write(p []byte) (int, error) {
+	if pw.level == LevelDebug && !pw.l.dw.debug() {
+		return len(p), nil
+	}
+	if !pw.l.smp.allow(pw, p) {
+		return len(p), nil
+	}
+
+	var caller string
+	if pw.l.co.enabled() {
+		caller = callerLocation()
+	}
+	return pw.write(p, caller)
+}
+
+// write writes p with the prefix bypassing the sampler.
+func (pw prefixWriter) write(p []byte, caller string) (int, error) {
+	n := len(p)
+	if pw.l.c != nil {
+		pw.l.c.record(pw.level, pw.l.names, caller, p)
+	}
+	if caller != "" {
+		p = appendNote(p, caller)
+	}
+
 	lines := bytes.Split(p, []byte("\n"))
 	p2 := make([]byte, 0, (len(pw.prefix)+1)*len(lines)+len(p))
 
@@ -124,9 +207,9 @@ func (pw prefixWriter) Write(p []byte) (int, error) {
 		p2 = append(p2, '\n')
 	}
 
-	n, err := pw.w.Write(p2)
-	if n > len(p) {
-		n = len(p)
+	n2, err := pw.w.Write(p2)
+	if n2 < n {
+		n = n2
 	}
 	return n, err
 }
@@ -210,17 +293,39 @@ func (tbWriter) Fd() uintptr {
 	return os.Stderr.Fd()
 }
 
+// Path returns fp linked to the file it refers to for terminals that support
+// hyperlinks. See xterm.FileHyperlink.
+func (l *Logger) Path(fp string) string {
+	return xterm.FileHyperlink(l.env, l.w, fp, fp)
+}
+
+// RenderError returns err with each layer of its chain on its own line.
+// See xdefer.Render.
+//
+//	l.Error.Print(l.RenderError(err))
+func (l *Logger) RenderError(err error) string {
+	return xdefer.Render(l.env, l.w, err)
+}
+
 func (l *Logger) WithCCPrefix(s string) *Logger {
-	return l.withPrefix(xterm.CCPrefix(l.env, l.w, s))
+	return l.withPrefix(s, xterm.CCPrefix(l.env, l.w, s))
 }
 
 func (l *Logger) WithPrefix(caps, s string) *Logger {
-	return l.withPrefix(xterm.Prefix(l.env, l.w, caps, s))
+	return l.withPrefix(s, xterm.Prefix(l.env, l.w, caps, s))
+}
+
+// withField adds the prefix key=value with only key colored so that a field keeps
+// the same color whatever its value.
+func (l *Logger) withField(key, value string) *Logger {
+	ckey := strings.TrimSuffix(xterm.CCPrefix(l.env, l.w, key), ":")
+	return l.withPrefix(key+"="+value, ckey+"="+value+":")
 }
 
-func (l *Logger) withPrefix(s string) *Logger {
+func (l *Logger) withPrefix(name, s string) *Logger {
 	l2 := new(Logger)
 	*l2 = *l
+	l2.names = append(l.names[:len(l.names):len(l.names)], name)
 
 	prefix := l.NoLevel.Writer().(prefixWriter).prefix
 	if len(s) > 0 {
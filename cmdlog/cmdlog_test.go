@@ -5,14 +5,20 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"log"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/creack/pty"
+	"golang.org/x/xerrors"
 
 	"oss.terrastruct.com/util-go/assert"
 	"oss.terrastruct.com/util-go/cmdlog"
+	"oss.terrastruct.com/util-go/xdefer"
 	"oss.terrastruct.com/util-go/xos"
+	"oss.terrastruct.com/util-go/xterm"
 )
 
 func TestLogger(t *testing.T) {
@@ -94,6 +100,190 @@ func TestLogger(t *testing.T) {
 				l.Info.Printf("what's up")
 			},
 		},
+		{
+			name: "Capture",
+			run: func(t *testing.T, ctx context.Context, env *xos.Env) {
+				l, c := cmdlog.NewCapture(env, t)
+				l = l.WithCCPrefix("lochness")
+				testLogger(l)
+
+				c.AssertContains(t, cmdlog.LevelWarn, "Telephone books")
+				c.AssertContains(t, cmdlog.LevelDebug, "rational animal")
+				c.AssertNotContains(t, cmdlog.LevelDebug, "trust a woman")
+				c.AssertNotContains(t, cmdlog.LevelInfo, "Telephone books")
+				assert.Equal(t, 2, len(c.Level(cmdlog.LevelError)))
+
+				assert.TestdataJSON(t, c.Entries())
+
+				c.Reset()
+				assert.Equal(t, 0, len(c.Entries()))
+			},
+		},
+		{
+			name: "Progress",
+			run: func(t *testing.T, ctx context.Context, env *xos.Env) {
+				b := &bytes.Buffer{}
+				env.Setenv("COLOR", "1")
+				l := cmdlog.New(env, b).WithCCPrefix("render")
+
+				p := l.NewProgress("rendering", 4)
+				p.Add(1)
+				l.Info.Print("rendered a")
+				s := l.NewProgress("uploading", 0)
+				p.Add(2)
+				l.Warn.Print("slow upload")
+				s.Done()
+				p.Done()
+				p.Done()
+				l.Success.Print("done")
+
+				t.Log(b.String())
+				assert.TestdataJSON(t, b.String())
+			},
+		},
+		{
+			name: "Progress/plain",
+			run: func(t *testing.T, ctx context.Context, env *xos.Env) {
+				b := &bytes.Buffer{}
+				l := cmdlog.New(env, b)
+
+				p := l.NewProgress("rendering", 4)
+				l.Info.Print("rendered a")
+				p.Set(4)
+				p.Done()
+				s := l.NewProgress("uploading", 0)
+				s.Done()
+
+				assert.String(t, `info: rendering: 0/4 (0%)
+info: rendered a
+info: rendering: 4/4 (100%)
+info: uploading (0s)
+info: uploading (0s)
+`, b.String())
+			},
+		},
+		{
+			name: "SetDedup",
+			run: func(t *testing.T, ctx context.Context, env *xos.Env) {
+				b := &bytes.Buffer{}
+				l := cmdlog.New(env, b)
+				l.SetDedup(time.Hour)
+				l2 := l.WithCCPrefix("retry")
+
+				for i := 0; i < 5; i++ {
+					l2.Error.Print("connection refused")
+					l2.Warn.Print("connection refused")
+				}
+				l.Error.Print("connection refused")
+				l.Close()
+				l2.Error.Print("connection refused")
+
+				assert.String(t, `retry: err: connection refused
+retry: warn: connection refused
+err: connection refused
+retry: err: connection refused (repeated 4 times)
+retry: warn: connection refused (repeated 4 times)
+retry: err: connection refused
+`, b.String())
+			},
+		},
+		{
+			name: "SetRateLimit",
+			run: func(t *testing.T, ctx context.Context, env *xos.Env) {
+				b := &bytes.Buffer{}
+				l := cmdlog.New(env, b)
+				l.SetRateLimit(2, time.Hour)
+				l2 := l.WithCCPrefix("worker")
+
+				for i := 0; i < 5; i++ {
+					l2.Info.Printf("job %d", i)
+				}
+				l.Info.Print("still here")
+				l.Close()
+
+				assert.String(t, `worker: info: job 0
+worker: info: job 1
+info: still here
+worker: warn: dropped 3 entries over the rate limit of 2 per 1h0m0s
+`, b.String())
+			},
+		},
+		{
+			name: "context",
+			run: func(t *testing.T, ctx context.Context, env *xos.Env) {
+				if cmdlog.FromContext(ctx) == nil {
+					t.Fatal("expected process wide logger")
+				}
+
+				l, c := cmdlog.NewCapture(env, t)
+				ctx = cmdlog.WithContext(ctx, l)
+				ctx = cmdlog.WithField(ctx, "req", "abc")
+				ctx = cmdlog.WithField(ctx, "user", "jane")
+				cmdlog.FromContext(ctx).Info.Print("hello")
+
+				assert.StringJSON(t, `[
+  "req=abc",
+  "user=jane"
+]`, c.Entries()[0].Prefixes)
+			},
+		},
+		{
+			name: "fieldColor",
+			run: func(t *testing.T, ctx context.Context, env *xos.Env) {
+				env.Setenv("COLOR", "1")
+				b := &bytes.Buffer{}
+				ctx = cmdlog.WithContext(ctx, cmdlog.New(env, b))
+				for _, id := range []string{"abc", "xyz"} {
+					cmdlog.FromContext(cmdlog.WithField(ctx, "req", id)).Info.Print("hello")
+				}
+
+				// Only the key is colored so it has the same color whatever the value.
+				lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+				assert.Equal(t, 2, len(lines))
+				key := strings.TrimSuffix(xterm.CCPrefix(env, b, "req"), ":")
+				for i, id := range []string{"abc", "xyz"} {
+					if !strings.HasPrefix(lines[i], key+"="+id+":") {
+						t.Fatalf("expected %q to start with %q", lines[i], key+"="+id+":")
+					}
+				}
+			},
+		},
+		{
+			name: "caller",
+			run: func(t *testing.T, ctx context.Context, env *xos.Env) {
+				b := &bytes.Buffer{}
+				l := cmdlog.New(env, b)
+
+				l.Info.Print("off")
+				env.Setenv("CMDLOG_CALLER", "1")
+				l.Info.Print("env")
+				_, _, line, _ := runtime.Caller(0)
+				env.Setenv("CMDLOG_CALLER", "")
+				l.SetCaller(true)
+				log.New(l.Warn.Writer(), "", 0).Print("setter")
+
+				exp := fmt.Sprintf(`info: off
+info: env (cmdlog/cmdlog_test.go:%d)
+warn: setter (cmdlog/cmdlog_test.go:%d)
+`, line-1, line+3)
+				assert.String(t, exp, b.String())
+
+				err := func() (err error) {
+					defer xdefer.Errorf(&err, "failed to render")
+					return xerrors.New("no such shape")
+				}()
+				b.Reset()
+				l.Error.Printf("%v", l.Trace(err))
+				if !strings.Contains(b.String(), "cmdlog_test.TestLogger") {
+					t.Fatalf("expected frames in trace: %q", b.String())
+				}
+
+				b.Reset()
+				l.SetCaller(false)
+				l.Error.Printf("%v", l.Trace(err))
+				assert.String(t, "err: failed to render: no such shape\n", b.String())
+			},
+		},
 		{
 			name: "WithPrefix",
 			run: func(t *testing.T, ctx context.Context, env *xos.Env) {
@@ -0,0 +1,29 @@
+package cmdlog
+
+import "context"
+
+type ctxKey struct{}
+
+// std is the process wide Logger to stderr created in init.
+var std *Logger
+
+// WithContext returns a copy of ctx carrying l. Retrieve it with FromContext.
+func WithContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the Logger carried by ctx. If ctx does not carry a Logger,
+// the process wide Logger to stderr is returned.
+func FromContext(ctx context.Context) *Logger {
+	l, ok := ctx.Value(ctxKey{}).(*Logger)
+	if ok {
+		return l
+	}
+	return std
+}
+
+// WithField returns a copy of ctx carrying the Logger from FromContext with key=value
+// added as a prefix. Use it to attach request scoped fields like a request ID.
+func WithField(ctx context.Context, key, value string) context.Context {
+	return WithContext(ctx, FromContext(ctx).withField(key, value))
+}
@@ -17,6 +17,9 @@ func init() {
 	timeNow = func() time.Time {
 		return time.Date(2000, time.January, 1, 1, 1, 1, 1, time.UTC)
 	}
+	// Progress is only drawn or logged in response to writes in tests.
+	progressTick = time.Hour
+	progressLogInterval = time.Hour
 }
 
 func TestStdlog(t *testing.T) {
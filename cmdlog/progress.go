@@ -0,0 +1,259 @@
+package cmdlog
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"oss.terrastruct.com/util-go/xterm"
+)
+
+var (
+	// progressTick is how often progress is redrawn on a TTY.
+	progressTick = time.Millisecond * 100
+	// progressLogInterval is how often progress is logged when not on a TTY.
+	progressLogInterval = time.Second * 5
+)
+
+const progressBarWidth = 30
+
+var spinnerFrames = [...]string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// Progress is a progress bar or spinner drawn below the output of a Logger.
+//
+// On a TTY it is redrawn continuously at the bottom of the output and cleared
+// whenever an entry is written through any level of the Logger it was created from.
+// Otherwise its status is logged periodically at the info level.
+//
+// $COLOR and $TERM=dumb are obeyed like in xterm.Tput.
+type Progress struct {
+	l      *Logger
+	prefix string
+	total  int64
+	start  time.Time
+	tty    bool
+
+	mu      sync.Mutex
+	msg     string
+	current int64
+
+	once sync.Once
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewProgress starts a progress bar for total units of work described by msg.
+// If total <= 0 a spinner is shown instead.
+//
+// Call Done when the work is complete.
+func (l *Logger) NewProgress(msg string, total int64) *Progress {
+	p := &Progress{
+		l:      l,
+		prefix: l.NoLevel.Writer().(prefixWriter).prefix,
+		total:  total,
+		start:  timeNow(),
+		msg:    msg,
+		tty:    xterm.ShouldColor(l.env, l.w),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	if p.tty {
+		l.pgw.add(p)
+	} else {
+		l.Info.Print(p.status())
+	}
+	go p.run()
+	return p
+}
+
+func (p *Progress) run() {
+	defer close(p.done)
+
+	interval := progressLogInterval
+	if p.tty {
+		interval = progressTick
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-t.C:
+			if p.tty {
+				p.l.pgw.redraw()
+			} else {
+				p.l.Info.Print(p.status())
+			}
+		}
+	}
+}
+
+// Add adds n to the completed units of work.
+func (p *Progress) Add(n int64) {
+	p.mu.Lock()
+	p.current += n
+	p.mu.Unlock()
+}
+
+// Set sets the completed units of work to n.
+func (p *Progress) Set(n int64) {
+	p.mu.Lock()
+	p.current = n
+	p.mu.Unlock()
+}
+
+// SetMessage replaces the message describing the work.
+func (p *Progress) SetMessage(msg string) {
+	p.mu.Lock()
+	p.msg = msg
+	p.mu.Unlock()
+}
+
+// Done stops and clears p. When not on a TTY the final status is logged.
+// It is safe to call more than once.
+func (p *Progress) Done() {
+	p.once.Do(func() {
+		close(p.stop)
+		<-p.done
+		if p.tty {
+			p.l.pgw.remove(p)
+		} else {
+			p.l.Info.Print(p.status())
+		}
+	})
+}
+
+func (p *Progress) get() (string, int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.msg, p.current
+}
+
+func (p *Progress) percent(current int64) int64 {
+	if current >= p.total {
+		return 100
+	}
+	if current <= 0 {
+		return 0
+	}
+	return current * 100 / p.total
+}
+
+// status is the plain text logged when not on a TTY.
+func (p *Progress) status() string {
+	msg, current := p.get()
+	if p.total <= 0 {
+		return fmt.Sprintf("%s (%v)", msg, timeNow().Sub(p.start).Round(time.Second))
+	}
+	return fmt.Sprintf("%s: %d/%d (%d%%)", msg, current, p.total, p.percent(current))
+}
+
+// line is the line drawn on a TTY.
+func (p *Progress) line() string {
+	msg, current := p.get()
+
+	var sb strings.Builder
+	if p.prefix != "" {
+		sb.WriteString(p.prefix + " ")
+	}
+	if p.total <= 0 {
+		frame := int(timeNow().Sub(p.start)/progressTick) % len(spinnerFrames)
+		sb.WriteString(xterm.Tput(p.l.env, p.l.w, xterm.Cyan, spinnerFrames[frame]))
+		sb.WriteString(" " + msg)
+		return sb.String()
+	}
+
+	pct := p.percent(current)
+	filled := int(pct * progressBarWidth / 100)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	fmt.Fprintf(&sb, "[%s] %3d%% %s", xterm.Tput(p.l.env, p.l.w, xterm.Green, bar), pct, msg)
+	return sb.String()
+}
+
+// progressWriter clears the active progress lines before each write to w
+// and redraws them after.
+type progressWriter struct {
+	w io.Writer
+
+	mu     sync.Mutex
+	active []*Progress
+	drawn  int
+}
+
+func (pgw *progressWriter) Write(p []byte) (int, error) {
+	pgw.mu.Lock()
+	defer pgw.mu.Unlock()
+
+	if pgw.drawn == 0 {
+		return pgw.w.Write(p)
+	}
+	pgw.clear()
+	n, err := pgw.w.Write(p)
+	pgw.draw()
+	return n, err
+}
+
+func (pgw *progressWriter) add(p *Progress) {
+	pgw.mu.Lock()
+	defer pgw.mu.Unlock()
+
+	pgw.clear()
+	pgw.active = append(pgw.active, p)
+	pgw.draw()
+}
+
+func (pgw *progressWriter) remove(p *Progress) {
+	pgw.mu.Lock()
+	defer pgw.mu.Unlock()
+
+	pgw.clear()
+	for i, p2 := range pgw.active {
+		if p2 == p {
+			pgw.active = append(pgw.active[:i], pgw.active[i+1:]...)
+			break
+		}
+	}
+	pgw.draw()
+}
+
+func (pgw *progressWriter) redraw() {
+	pgw.mu.Lock()
+	defer pgw.mu.Unlock()
+
+	pgw.clear()
+	pgw.draw()
+}
+
+// clear erases the drawn progress lines and leaves the cursor at the start
+// of the first.
+func (pgw *progressWriter) clear() {
+	if pgw.drawn == 0 {
+		return
+	}
+	s := "\r" + xterm.EraseLine + strings.Repeat(xterm.CursorUp+xterm.EraseLine, pgw.drawn-1)
+	_, _ = io.WriteString(pgw.w, s)
+	pgw.drawn = 0
+}
+
+// draw writes a line for each active progress. Lines are truncated to the width of
+// the terminal as clear assumes each occupies a single row.
+func (pgw *progressWriter) draw() {
+	if len(pgw.active) == 0 {
+		return
+	}
+	size, err := xterm.Size(pgw.w)
+	lines := make([]string, len(pgw.active))
+	for i, p := range pgw.active {
+		lines[i] = p.line()
+		if err == nil && size.Width > 0 {
+			lines[i] = xterm.Truncate(lines[i], size.Width)
+		}
+	}
+	_, _ = io.WriteString(pgw.w, strings.Join(lines, "\n"))
+	pgw.drawn = len(lines)
+}
@@ -0,0 +1,41 @@
+//go:build !windows
+
+package cmdlog_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"oss.terrastruct.com/util-go/assert"
+	"oss.terrastruct.com/util-go/cmdlog"
+	"oss.terrastruct.com/util-go/xos"
+	"oss.terrastruct.com/util-go/xpty"
+	"oss.terrastruct.com/util-go/xterm"
+)
+
+func TestProgressTruncate(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	term := xpty.Open(t, xterm.WinSize{Width: 40, Height: 10})
+	l := cmdlog.New(xos.NewEnv([]string{"COLOR=1"}), term.TTY())
+
+	p := l.NewProgress("rendering a diagram with a very long name", 4)
+	l.Info.Print("rendered a")
+	p.Done()
+	l.Info.Print("done")
+
+	err := term.Expect(ctx, "done")
+	assert.Success(t, err)
+	out := xterm.Strip(term.Output())
+	if !strings.Contains(out, "]   0% r…\r") {
+		t.Fatalf("expected progress truncated to 40 columns: %q", out)
+	}
+	if strings.Contains(out, "very long name") {
+		t.Fatalf("expected progress truncated to 40 columns: %q", out)
+	}
+}
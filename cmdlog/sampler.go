@@ -0,0 +1,199 @@
+package cmdlog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SetDedup collapses identical entries logged within window of the first into the
+// first followed by a summary with the number of times it was repeated once window
+// ends. Entries are identical if their level, prefixes and message are equal.
+//
+// Pass 0 to disable which is the default.
+func (l *Logger) SetDedup(window time.Duration) {
+	l.smp.mu.Lock()
+	l.smp.window = window
+	l.smp.mu.Unlock()
+}
+
+// SetRateLimit limits each chain of prefixes to n entries per interval. Entries over
+// the limit are dropped and a warning with the number dropped is logged once interval
+// ends.
+//
+// Pass n == 0 to disable which is the default.
+func (l *Logger) SetRateLimit(n int, interval time.Duration) {
+	l.smp.mu.Lock()
+	l.smp.limit = n
+	l.smp.interval = interval
+	l.smp.mu.Unlock()
+}
+
+// Close immediately logs the pending summaries of entries collapsed by SetDedup
+// or dropped by SetRateLimit. The Logger remains usable.
+//
+// xmain.Main calls Close before exiting.
+func (l *Logger) Close() error {
+	l.smp.flush()
+	return nil
+}
+
+type sampler struct {
+	mu       sync.Mutex
+	window   time.Duration
+	limit    int
+	interval time.Duration
+
+	seq     int
+	samples map[string]*sample
+	rates   map[string]*rate
+}
+
+// sample is an entry being deduplicated.
+type sample struct {
+	seq      int
+	pw       prefixWriter
+	p        []byte
+	repeated int
+	timer    *time.Timer
+}
+
+// rate counts the entries of a prefix chain in the current interval.
+type rate struct {
+	seq      int
+	l        *Logger
+	limit    int
+	interval time.Duration
+	n        int
+	dropped  int
+	timer    *time.Timer
+}
+
+// allow reports whether p should be written through pw.
+func (smp *sampler) allow(pw prefixWriter, p []byte) bool {
+	smp.mu.Lock()
+	defer smp.mu.Unlock()
+
+	if smp.window <= 0 && smp.limit <= 0 {
+		return true
+	}
+
+	names := strings.Join(pw.l.names, "\x00")
+	sampleKey := fmt.Sprintf("%d\x00%s\x00%s", pw.level, names, p)
+	if s, ok := smp.samples[sampleKey]; ok {
+		s.repeated++
+		return false
+	}
+
+	if smp.limit > 0 {
+		r, ok := smp.rates[names]
+		if !ok {
+			if smp.rates == nil {
+				smp.rates = make(map[string]*rate)
+			}
+			smp.seq++
+			r = &rate{
+				seq:      smp.seq,
+				l:        pw.l,
+				limit:    smp.limit,
+				interval: smp.interval,
+			}
+			r.timer = time.AfterFunc(smp.interval, func() {
+				if smp.removeRate(names, r) {
+					r.summarize()
+				}
+			})
+			smp.rates[names] = r
+		}
+		r.n++
+		if r.n > r.limit {
+			r.dropped++
+			return false
+		}
+	}
+
+	if smp.window > 0 {
+		if smp.samples == nil {
+			smp.samples = make(map[string]*sample)
+		}
+		smp.seq++
+		s := &sample{
+			seq: smp.seq,
+			pw:  pw,
+			p:   append([]byte(nil), p...),
+		}
+		s.timer = time.AfterFunc(smp.window, func() {
+			if smp.removeSample(sampleKey, s) {
+				s.summarize()
+			}
+		})
+		smp.samples[sampleKey] = s
+	}
+	return true
+}
+
+// removeSample deletes the sample at k if it is still s.
+func (smp *sampler) removeSample(k string, s *sample) bool {
+	smp.mu.Lock()
+	defer smp.mu.Unlock()
+	if smp.samples[k] != s {
+		return false
+	}
+	delete(smp.samples, k)
+	return true
+}
+
+// removeRate deletes the rate at k if it is still r.
+func (smp *sampler) removeRate(k string, r *rate) bool {
+	smp.mu.Lock()
+	defer smp.mu.Unlock()
+	if smp.rates[k] != r {
+		return false
+	}
+	delete(smp.rates, k)
+	return true
+}
+
+func (smp *sampler) flush() {
+	smp.mu.Lock()
+	samples, rates := smp.samples, smp.rates
+	smp.samples, smp.rates = nil, nil
+	smp.mu.Unlock()
+
+	type summary struct {
+		seq       int
+		summarize func()
+	}
+	var summaries []summary
+	for _, s := range samples {
+		s.timer.Stop()
+		summaries = append(summaries, summary{s.seq, s.summarize})
+	}
+	for _, r := range rates {
+		r.timer.Stop()
+		summaries = append(summaries, summary{r.seq, r.summarize})
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].seq < summaries[j].seq
+	})
+	for _, s := range summaries {
+		s.summarize()
+	}
+}
+
+func (s *sample) summarize() {
+	if s.repeated == 0 {
+		return
+	}
+	_, _ = s.pw.write(appendNote(s.p, fmt.Sprintf("repeated %d times", s.repeated)), "")
+}
+
+func (r *rate) summarize() {
+	if r.dropped == 0 {
+		return
+	}
+	msg := fmt.Sprintf("dropped %d entries over the rate limit of %d per %v\n", r.dropped, r.limit, r.interval)
+	_, _ = r.l.Warn.Writer().(prefixWriter).write([]byte(msg), "")
+}
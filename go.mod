@@ -3,6 +3,7 @@ module oss.terrastruct.com/util-go
 go 1.18
 
 require (
+	github.com/BurntSushi/toml v1.2.1
 	github.com/creack/pty v1.1.18
 	github.com/pkg/browser v0.0.0-20210911075715-681adbf594b8
 	github.com/spf13/pflag v1.0.5
@@ -11,6 +12,7 @@ require (
 	golang.org/x/term v0.2.0
 	golang.org/x/text v0.4.0
 	golang.org/x/xerrors v0.0.0-20220907171357-04be3eba64a2
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,138 @@
+package xdefer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/xerrors"
+
+	"oss.terrastruct.com/util-go/xos"
+	"oss.terrastruct.com/util-go/xterm"
+)
+
+// Render formats err with each layer of its chain on its own line, each indented
+// further than the last. The innermost error, the root cause, is colored red.
+//
+// When $DEBUG is set, the frames captured by Errorf and xerrors are shown dimmed
+// beneath the layer they belong to.
+//
+// Color is only used if xterm.ShouldColor(env, w).
+func Render(env *xos.Env, w io.Writer, err error) string {
+	if err == nil {
+		return ""
+	}
+
+	layers := chain(err)
+	debug := env.Debug()
+
+	var sb strings.Builder
+	for i, l := range layers {
+		indent := strings.Repeat("  ", i)
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+		msg := l.msg
+		if i < len(layers)-1 {
+			msg += ":"
+		} else {
+			msg = xterm.Tput(env, w, xterm.Red, msg)
+		}
+		sb.WriteString(indent + msg)
+		if debug {
+			for _, f := range l.frames {
+				sb.WriteString("\n" + indent + "  " + xterm.Tput(env, w, xterm.Dim, f))
+			}
+		}
+	}
+	return sb.String()
+}
+
+type layer struct {
+	msg    string
+	frames []string
+}
+
+// chain splits err into its layers. Layers without a message, like those from
+// Errorf(&err, ""), have their frames merged into the layer before them or into
+// the first layer with a message if they are outermost.
+func chain(root error) []layer {
+	var layers []layer
+	var pending []string
+	err := root
+	for err != nil {
+		var l layer
+		var next error
+		if f, ok := err.(xerrors.Formatter); ok {
+			p := &layerPrinter{}
+			next = f.FormatError(p)
+			l.msg = p.msg.String()
+			l.frames = p.frames()
+		} else {
+			next = xerrors.Unwrap(err)
+			l.msg = err.Error()
+			if next != nil {
+				// fmt.Errorf includes the message of the wrapped error in its own.
+				l.msg = strings.TrimSuffix(l.msg, ": "+next.Error())
+			}
+		}
+		err = next
+
+		if l.msg == "" {
+			if len(layers) > 0 {
+				prev := &layers[len(layers)-1]
+				prev.frames = append(prev.frames, l.frames...)
+			} else {
+				pending = append(pending, l.frames...)
+			}
+			continue
+		}
+		l.frames = append(pending, l.frames...)
+		pending = nil
+		layers = append(layers, l)
+	}
+	if len(layers) == 0 {
+		layers = append(layers, layer{msg: root.Error(), frames: pending})
+	}
+	return layers
+}
+
+// layerPrinter implements xerrors.Printer to capture the message and frames of a
+// single layer of an error chain.
+type layerPrinter struct {
+	msg      strings.Builder
+	detail   strings.Builder
+	inDetail bool
+}
+
+func (p *layerPrinter) Print(v ...interface{}) {
+	p.buf().WriteString(fmt.Sprint(v...))
+}
+
+func (p *layerPrinter) Printf(f string, v ...interface{}) {
+	p.buf().WriteString(fmt.Sprintf(f, v...))
+}
+
+func (p *layerPrinter) Detail() bool {
+	p.inDetail = true
+	return true
+}
+
+func (p *layerPrinter) buf() *strings.Builder {
+	if p.inDetail {
+		return &p.detail
+	}
+	return &p.msg
+}
+
+// frames returns each frame of the detail as function followed by file:line.
+func (p *layerPrinter) frames() []string {
+	lines := strings.Split(strings.TrimSpace(p.detail.String()), "\n")
+	var frames []string
+	for i := 0; i+1 < len(lines); i += 2 {
+		fn := strings.TrimSpace(lines[i])
+		loc := strings.TrimSpace(lines[i+1])
+		frames = append(frames, fn+" "+loc)
+	}
+	return frames
+}
@@ -0,0 +1,75 @@
+package xdefer_test
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+
+	"golang.org/x/xerrors"
+
+	"oss.terrastruct.com/util-go/assert"
+	"oss.terrastruct.com/util-go/xdefer"
+	"oss.terrastruct.com/util-go/xos"
+	"oss.terrastruct.com/util-go/xterm"
+)
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+
+	err := func() (err error) {
+		defer xdefer.Errorf(&err, "failed to compile %q", "x.d2")
+
+		err = func() (err error) {
+			defer xdefer.Errorf(&err, "")
+			return fmt.Errorf("failed to parse: %w", xerrors.New("unexpected }"))
+		}()
+		return xerrors.Errorf("failed to render: %w", err)
+	}()
+
+	env := xos.NewEnv(nil)
+	assert.String(t, `failed to compile "x.d2":
+  failed to render:
+    failed to parse:
+      unexpected }`, xdefer.Render(env, &bytes.Buffer{}, err))
+
+	env.Setenv("COLOR", "1")
+	got := xdefer.Render(env, &bytes.Buffer{}, err)
+	if !strings.HasSuffix(got, xterm.Tput(env, &bytes.Buffer{}, xterm.Red, "unexpected }")) {
+		t.Fatalf("expected red root cause: %q", got)
+	}
+
+	env.Setenv("COLOR", "0")
+	env.Setenv("DEBUG", "1")
+	_, fp, _, ok := runtime.Caller(0)
+	if !ok {
+		t.Fatal("runtime.Caller failed")
+	}
+	// The exact frames depend on inlining and -race so only their form is checked.
+	frameRegex := regexp.MustCompile(`^ +oss\.terrastruct\.com/util-go/xdefer_test\.TestRender\.func1(\.1)? ` + regexp.QuoteMeta(fp) + `:\d+$`)
+	var msgs []string
+	frames := 0
+	lines := strings.Split(xdefer.Render(env, &bytes.Buffer{}, err), "\n")
+	for _, l := range lines {
+		if !strings.Contains(l, fp) {
+			msgs = append(msgs, l)
+			continue
+		}
+		if !frameRegex.MatchString(l) {
+			t.Fatalf("unexpected frame: %q", l)
+		}
+		frames++
+	}
+	assert.String(t, `failed to compile "x.d2":
+  failed to render:
+    failed to parse:
+      unexpected }`, strings.Join(msgs, "\n"))
+	if frames < 2 {
+		t.Fatalf("expected frames in %q", lines)
+	}
+	if last := lines[len(lines)-1]; !strings.Contains(last, "TestRender.func1.1 ") {
+		t.Fatalf("expected the root cause to end with its frame: %q", last)
+	}
+}
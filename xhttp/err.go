@@ -67,6 +67,8 @@ func (e Error) Error() string {
 type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
 
 type HandlerFuncAdapter struct {
+	// Log is used to log errors. If nil, the Logger from cmdlog.FromContext
+	// on the request's context is used. See xhttp.Log.
 	Log  *cmdlog.Logger
 	Func HandlerFunc
 }
@@ -92,7 +94,11 @@ func (a HandlerFuncAdapter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	var h http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		err := a.Func(w, r)
 		if err != nil {
-			handleError(a.Log, w, err)
+			clog := a.Log
+			if clog == nil {
+				clog = cmdlog.FromContext(r.Context())
+			}
+			handleError(clog, w, err)
 		}
 	})
 
@@ -7,14 +7,18 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"regexp"
 	"runtime/debug"
 	"time"
 
 	"golang.org/x/text/message"
 
 	"oss.terrastruct.com/util-go/cmdlog"
+	"oss.terrastruct.com/util-go/xrand"
 )
 
+var requestIDRegex = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
 type ResponseWriter interface {
 	http.ResponseWriter
 	http.Hijacker
@@ -75,9 +79,23 @@ func (rw *responseWriter) Written() bool {
 	return rw.written
 }
 
+// Log logs every request to clog at a level for its status code and recovers panics.
+//
+// Each request's context carries clog with a req=<id> prefix where the ID is from the
+// X-Request-ID header or randomly generated. Retrieve it with cmdlog.FromContext.
+// The header is only trusted if it matches requestIDRegex so that clients cannot
+// write escape sequences or junk into the logs.
 func Log(clog *cmdlog.Logger, next http.Handler) http.Handler {
 	englishPrinter := message.NewPrinter(message.MatchLanguage("en"))
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqID := r.Header.Get("X-Request-ID")
+		if !requestIDRegex.MatchString(reqID) {
+			reqID = xrand.Base64(6)
+		}
+		ctx := cmdlog.WithField(cmdlog.WithContext(r.Context(), clog), "req", reqID)
+		clog := cmdlog.FromContext(ctx)
+		r = r.WithContext(ctx)
+
 		defer func() {
 			rec := recover()
 			if rec != nil {
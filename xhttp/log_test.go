@@ -0,0 +1,126 @@
+package xhttp_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"oss.terrastruct.com/util-go/assert"
+	"oss.terrastruct.com/util-go/cmdlog"
+	"oss.terrastruct.com/util-go/xhttp"
+	"oss.terrastruct.com/util-go/xos"
+)
+
+func TestLog(t *testing.T) {
+	t.Parallel()
+
+	tca := []struct {
+		name string
+		err  error
+
+		expCode  int
+		expLevel cmdlog.Level
+		expMsg   string
+	}{
+		{
+			name:     "ok",
+			expCode:  http.StatusOK,
+			expLevel: cmdlog.LevelSuccess,
+			expMsg:   "GET /ok 200 ",
+		},
+		{
+			name:     "4xx",
+			err:      xhttp.Errorf(http.StatusNotFound, nil, "no such diagram"),
+			expCode:  http.StatusNotFound,
+			expLevel: cmdlog.LevelWarn,
+			expMsg:   "no such diagram",
+		},
+		{
+			name:     "5xx",
+			err:      xhttp.Errorf(http.StatusBadGateway, nil, "upstream down"),
+			expCode:  http.StatusBadGateway,
+			expLevel: cmdlog.LevelError,
+			expMsg:   "upstream down",
+		},
+		{
+			name:     "unwrapped",
+			err:      errors.New("disk full"),
+			expCode:  http.StatusInternalServerError,
+			expLevel: cmdlog.LevelError,
+			expMsg:   "disk full",
+		},
+	}
+
+	for _, tc := range tca {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			clog, c := cmdlog.NewCapture(xos.NewEnv(nil), t)
+			h := xhttp.Log(clog, xhttp.HandlerFuncAdapter{
+				Log: clog,
+				Func: func(w http.ResponseWriter, r *http.Request) error {
+					if tc.err != nil {
+						return tc.err
+					}
+					xhttp.JSON(clog, w, http.StatusOK, nil)
+					return nil
+				},
+			})
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest("GET", "/"+tc.name, nil))
+
+			assert.Equal(t, tc.expCode, rec.Code)
+			c.AssertContains(t, tc.expLevel, tc.expMsg)
+			if tc.expLevel != cmdlog.LevelError {
+				assert.Equal(t, 0, len(c.Level(cmdlog.LevelError)))
+			}
+		})
+	}
+}
+
+func TestLogContext(t *testing.T) {
+	t.Parallel()
+
+	clog, c := cmdlog.NewCapture(xos.NewEnv(nil), t)
+	h := xhttp.Log(clog, xhttp.HandlerFuncAdapter{
+		Func: func(w http.ResponseWriter, r *http.Request) error {
+			cmdlog.FromContext(r.Context()).Info.Print("rendering")
+			return xhttp.Errorf(http.StatusBadRequest, nil, "bad diagram")
+		},
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-ID", "abc")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := c.Entries()
+	assert.Equal(t, 3, len(entries))
+	for _, e := range entries {
+		assert.StringJSON(t, `[
+  "req=abc"
+]`, e.Prefixes)
+	}
+	c.AssertContains(t, cmdlog.LevelInfo, "rendering")
+	c.AssertContains(t, cmdlog.LevelWarn, "bad diagram")
+
+	for _, hostile := range []string{"\x1b]2;pwned\x07", strings.Repeat("a", 65), "a b"} {
+		clog, c := cmdlog.NewCapture(xos.NewEnv(nil), t)
+		h := xhttp.Log(clog, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Request-ID", hostile)
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		entries := c.Entries()
+		assert.Equal(t, 1, len(entries))
+		for _, e := range entries {
+			assert.Equal(t, 1, len(e.Prefixes))
+			if e.Prefixes[0] == "req="+hostile || len(e.Prefixes[0]) != len("req=")+8 {
+				t.Fatalf("expected generated request ID for %q: %q", hostile, e.Prefixes[0])
+			}
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package xmain
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bind declares an option for each field of the struct pointed to by v that has a
+// flag tag. The options are parsed into the fields by Parse.
+//
+//	var opts struct {
+//		Port    int64         `flag:"port" short:"p" env:"PORT" default:"8080" usage:"port to listen on" min:"1" max:"65535"`
+//		Format  string        `flag:"format" oneof:"json yaml" default:"json" usage:"output format"`
+//		Timeout time.Duration `flag:"timeout" env:"TIMEOUT" usage:"request timeout" required:"true"`
+//		Log     struct {
+//			Level string `flag:"level" env:"LEVEL" usage:"log level"`
+//		} `flag:"log" env:"LOG"`
+//	}
+//	err := ms.Opts.Bind(&opts)
+//
+// The supported field types are those of the Opts methods: string, bool, int64,
+// []int64, float64, uint, time.Duration, []string and map[string]string. A string
+// field with type:"path" is declared with Path and an int64 field with
+// type:"bytesize" with ByteSize. oneof lists the values allowed for a string field
+// separated by spaces and declares it with Enum.
+//
+// default is parsed like the value of the flag. A field without a default tag
+// defaults to its current value.
+//
+// The flag and env tags of a nested struct prefix those of its fields. Above, Level
+// is bound to --log-level and $LOG_LEVEL.
+//
+// Parse reports a UsageError if a field tagged required is not set by a flag, the
+// environment or the config file or if a number or duration is out of the range
+// given by min and max.
+func (o *Opts) Bind(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("xmain: Bind expects a pointer to a struct but got %T", v)
+	}
+	return o.bindStruct(rv.Elem(), "", "")
+}
+
+func (o *Opts) bindStruct(rv reflect.Value, flagPrefix, envPrefix string) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		flag, ok := sf.Tag.Lookup("flag")
+		if !ok || !sf.IsExported() {
+			continue
+		}
+		env := sf.Tag.Get("env")
+		if env != "" {
+			env = envPrefix + env
+		}
+
+		fv := rv.Field(i)
+		if sf.Type.Kind() == reflect.Struct {
+			fp, ep := flagPrefix, envPrefix
+			if flag != "" {
+				fp += flag + "-"
+			}
+			if env != "" {
+				ep = env + "_"
+			}
+			err := o.bindStruct(fv, fp, ep)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err := o.bindField(fv.Addr().Interface(), sf.Tag, flagPrefix+flag, env)
+		if err != nil {
+			// Invalid values in the environment or config are not the fault of the
+			// struct.
+			var uerr UsageError
+			if errors.As(err, &uerr) {
+				return err
+			}
+			return fmt.Errorf("xmain: failed to bind field %s: %w", sf.Name, err)
+		}
+	}
+	return nil
+}
+
+func (o *Opts) bindField(p interface{}, tag reflect.StructTag, flag, env string) error {
+	short := tag.Get("short")
+	usage := tag.Get("usage")
+	def, hasDef := tag.Lookup("default")
+	typ := tag.Get("type")
+	oneof := strings.Fields(tag.Get("oneof"))
+
+	if len(oneof) > 0 {
+		if _, ok := p.(*string); !ok {
+			return errors.New("oneof is only supported on string fields")
+		}
+	}
+	if typ != "" {
+		_, isString := p.(*string)
+		_, isInt64 := p.(*int64)
+		if !(typ == "path" && isString) && !(typ == "bytesize" && isInt64) {
+			return fmt.Errorf("type %q is not supported on %T fields", typ, reflect.ValueOf(p).Elem().Interface())
+		}
+	}
+	if hasDef {
+		err := parseDefault(p, typ, def)
+		if err != nil {
+			return fmt.Errorf("invalid default %q: %w", def, err)
+		}
+	}
+
+	var err error
+	switch p := p.(type) {
+	case *string:
+		switch {
+		case typ == "path":
+			o.pathVar(p, env, flag, short, *p, usage)
+		case len(oneof) > 0:
+			err = o.enumVar(p, env, flag, short, *p, oneof, usage)
+		default:
+			o.stringVar(p, env, flag, short, *p, usage)
+		}
+	case *bool:
+		err = o.boolVar(p, env, flag, short, *p, usage)
+	case *int64:
+		if typ == "bytesize" {
+			err = o.byteSizeVar(p, env, flag, short, *p, usage)
+		} else {
+			err = o.int64Var(p, env, flag, short, *p, usage)
+		}
+	case *[]int64:
+		err = o.int64SliceVar(p, env, flag, short, *p, usage)
+	case *float64:
+		err = o.float64Var(p, env, flag, short, *p, usage)
+	case *uint:
+		err = o.uintVar(p, env, flag, short, *p, usage)
+	case *time.Duration:
+		err = o.durationVar(p, env, flag, short, *p, usage)
+	case *[]string:
+		o.stringSliceVar(p, env, flag, short, *p, usage)
+	case *map[string]string:
+		err = o.stringToStringVar(p, env, flag, short, *p, usage)
+	default:
+		return fmt.Errorf("unsupported type %T", reflect.ValueOf(p).Elem().Interface())
+	}
+	if err != nil {
+		return err
+	}
+
+	if required, _ := strconv.ParseBool(tag.Get("required")); required {
+		o.Required(flag)
+	}
+	return o.bindRange(p, typ, tag, flag)
+}
+
+// bindRange adds a validator for the min and max tags of a number or duration.
+func (o *Opts) bindRange(p interface{}, typ string, tag reflect.StructTag, flag string) error {
+	minTag, hasMin := tag.Lookup("min")
+	maxTag, hasMax := tag.Lookup("max")
+	if !hasMin && !hasMax {
+		return nil
+	}
+
+	if _, ok := numberValue(p); !ok {
+		return fmt.Errorf("min and max are not supported on %T fields", reflect.ValueOf(p).Elem().Interface())
+	}
+	bound := func(s string) (float64, error) {
+		bp := reflect.New(reflect.TypeOf(p).Elem()).Interface()
+		err := parseDefault(bp, typ, s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid bound %q: %w", s, err)
+		}
+		n, _ := numberValue(bp)
+		return n, nil
+	}
+
+	var lo, hi float64
+	var err error
+	if hasMin {
+		lo, err = bound(minTag)
+		if err != nil {
+			return err
+		}
+	}
+	if hasMax {
+		hi, err = bound(maxTag)
+		if err != nil {
+			return err
+		}
+	}
+	o.validators = append(o.validators, func(o *Opts) error {
+		v, _ := numberValue(p)
+		f := o.Flags.Lookup(flag)
+		switch {
+		case hasMin && v < lo:
+			return UsageErrorf(`invalid --%s. Expected at least %s. Found "%s".`, flag, minTag, f.Value)
+		case hasMax && v > hi:
+			return UsageErrorf(`invalid --%s. Expected at most %s. Found "%s".`, flag, maxTag, f.Value)
+		}
+		return nil
+	})
+	return nil
+}
+
+// numberValue returns the value p points to as a float64 if it is a number or
+// duration.
+func numberValue(p interface{}) (float64, bool) {
+	switch p := p.(type) {
+	case *int64:
+		return float64(*p), true
+	case *float64:
+		return *p, true
+	case *uint:
+		return float64(*p), true
+	case *time.Duration:
+		return float64(*p), true
+	}
+	return 0, false
+}
+
+// parseDefault parses s into p like a flag of the type of p.
+func parseDefault(p interface{}, typ string, s string) error {
+	var err error
+	switch p := p.(type) {
+	case *string:
+		*p = s
+	case *bool:
+		*p, err = strconv.ParseBool(s)
+	case *int64:
+		if typ == "bytesize" {
+			*p, err = parseByteSize(s)
+		} else {
+			*p, err = strconv.ParseInt(s, 10, 64)
+		}
+	case *[]int64:
+		*p = nil
+		for _, part := range splitList(s) {
+			n, err := strconv.ParseInt(part, 10, 64)
+			if err != nil {
+				return err
+			}
+			*p = append(*p, n)
+		}
+	case *float64:
+		*p, err = strconv.ParseFloat(s, 64)
+	case *uint:
+		var n uint64
+		n, err = strconv.ParseUint(s, 10, 0)
+		*p = uint(n)
+	case *time.Duration:
+		*p, err = time.ParseDuration(s)
+	case *[]string:
+		*p = splitList(s)
+	case *map[string]string:
+		*p = make(map[string]string)
+		for _, part := range splitList(s) {
+			k, v, ok := strings.Cut(part, "=")
+			if !ok {
+				return fmt.Errorf("expected key=value but got %q", part)
+			}
+			(*p)[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	default:
+		return fmt.Errorf("unsupported type %T", reflect.ValueOf(p).Elem().Interface())
+	}
+	return err
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	split := strings.Split(s, ",")
+	for i := range split {
+		split[i] = strings.TrimSpace(split[i])
+	}
+	return split
+}
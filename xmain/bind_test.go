@@ -0,0 +1,186 @@
+package xmain_test
+
+import (
+	"testing"
+	"time"
+
+	"oss.terrastruct.com/util-go/assert"
+	"oss.terrastruct.com/util-go/xmain"
+	"oss.terrastruct.com/util-go/xos"
+)
+
+type bindOpts struct {
+	Port    int64         `flag:"port" short:"p" env:"PORT" default:"8080" usage:"port to listen on" min:"1" max:"65535"`
+	Debug   bool          `flag:"debug" env:"DEBUG" usage:"enable debug logging"`
+	Format  string        `flag:"format" short:"f" env:"FORMAT" default:"json" oneof:"json yaml" usage:"output format"`
+	Timeout time.Duration `flag:"timeout" env:"TIMEOUT" default:"30s" usage:"request timeout" min:"1s"`
+	Tags    []string      `flag:"tags" env:"TAGS" usage:"tags"`
+	MaxSize int64         `flag:"max-size" env:"MAX_SIZE" default:"1MiB" type:"bytesize" usage:"max size"`
+	Out     string        `flag:"out" env:"OUT" default:"-" type:"path" usage:"output path"`
+	Log     struct {
+		Level string `flag:"level" env:"LEVEL" default:"info" usage:"log level"`
+		File  string `flag:"file" usage:"log file"`
+	} `flag:"log" env:"LOG"`
+
+	ignored string
+	NoFlag  string
+}
+
+func TestBind(t *testing.T) {
+	t.Parallel()
+
+	env := xos.NewEnv([]string{
+		"PORT=9000",
+		"LOG_LEVEL=warn",
+	})
+	o := xmain.NewOpts(env, []string{"-f", "yaml", "--tags=a,b", "--max-size=2KB", "--log-file", "x.log"})
+	var opts bindOpts
+	opts.Debug = true
+	err := o.Bind(&opts)
+	assert.Success(t, err)
+	err = o.Parse()
+	assert.Success(t, err)
+
+	assert.Equal(t, int64(9000), opts.Port)
+	assert.Equal(t, true, opts.Debug)
+	assert.Equal(t, "yaml", opts.Format)
+	assert.Equal(t, 30*time.Second, opts.Timeout)
+	assert.StringJSON(t, `[
+  "a",
+  "b"
+]`, opts.Tags)
+	assert.Equal(t, int64(2000), opts.MaxSize)
+	assert.Equal(t, "-", opts.Out)
+	assert.Equal(t, "warn", opts.Log.Level)
+	assert.Equal(t, "x.log", opts.Log.File)
+
+	// The help is the same as if the options were declared with the Opts methods.
+	o2 := xmain.NewOpts(env, nil)
+	_, err = o2.Int64("PORT", "port", "p", 8080, "port to listen on")
+	assert.Success(t, err)
+	_, err = o2.Bool("DEBUG", "debug", "", true, "enable debug logging")
+	assert.Success(t, err)
+	_, err = o2.Enum("FORMAT", "format", "f", "json", []string{"json", "yaml"}, "output format")
+	assert.Success(t, err)
+	_, err = o2.Duration("TIMEOUT", "timeout", "", 30*time.Second, "request timeout")
+	assert.Success(t, err)
+	o2.StringSlice("TAGS", "tags", "", nil, "tags")
+	_, err = o2.ByteSize("MAX_SIZE", "max-size", "", 1<<20, "max size")
+	assert.Success(t, err)
+	o2.Path("OUT", "out", "", "-", "output path")
+	o2.String("LOG_LEVEL", "log-level", "", "info", "log level")
+	o2.String("", "log-file", "", "", "log file")
+	err = o2.Parse()
+	assert.Success(t, err)
+	assert.String(t, o2.Defaults(), o.Defaults())
+}
+
+func TestBindErrors(t *testing.T) {
+	t.Parallel()
+
+	tca := []struct {
+		name    string
+		environ []string
+		args    []string
+		v       interface{}
+		exp     string
+	}{
+		{
+			name: "not_struct",
+			v:    new(string),
+			exp:  `xmain: Bind expects a pointer to a struct but got *string`,
+		},
+		{
+			name: "unsupported_type",
+			v: &struct {
+				N int `flag:"n"`
+			}{},
+			exp: `xmain: failed to bind field N: unsupported type int`,
+		},
+		{
+			name: "invalid_default",
+			v: &struct {
+				N int64 `flag:"n" default:"x"`
+			}{},
+			exp: `xmain: failed to bind field N: invalid default "x": strconv.ParseInt: parsing "x": invalid syntax`,
+		},
+		{
+			name: "default_bytesize_overflow",
+			v: &struct {
+				N int64 `flag:"n" type:"bytesize" default:"9000000TiB"`
+			}{},
+			exp: `xmain: failed to bind field N: invalid default "9000000TiB": size "9000000TiB" is too large`,
+		},
+		{
+			name: "oneof_default",
+			v: &struct {
+				Format string `flag:"format" oneof:"json yaml" default:"xml"`
+			}{},
+			exp: `xmain: failed to bind field Format: invalid default "xml" for --format: expected one of json, yaml`,
+		},
+		{
+			name: "oneof_int",
+			v: &struct {
+				N int64 `flag:"n" oneof:"1 2"`
+			}{},
+			exp: `xmain: failed to bind field N: oneof is only supported on string fields`,
+		},
+		{
+			name:    "invalid_env",
+			environ: []string{"PORT=http"},
+			v:       &bindOpts{},
+			exp:     `bad usage: invalid environment variable PORT. Expected int64. Found "http".`,
+		},
+		{
+			name: "required",
+			v: &struct {
+				Token string `flag:"token" env:"TOKEN" required:"true"`
+			}{},
+			exp: `bad usage: missing required flag --token or $TOKEN`,
+		},
+		{
+			name:    "required_env",
+			environ: []string{"TOKEN=x"},
+			v: &struct {
+				Token string `flag:"token" env:"TOKEN" required:"true"`
+			}{},
+		},
+		{
+			name: "min",
+			args: []string{"--port=0"},
+			v:    &bindOpts{},
+			exp:  `bad usage: invalid --port. Expected at least 1. Found "0".`,
+		},
+		{
+			name: "max_duration",
+			args: []string{"--timeout=2h"},
+			v: &struct {
+				Timeout time.Duration `flag:"timeout" max:"1h"`
+			}{},
+			exp: `bad usage: invalid --timeout. Expected at most 1h. Found "2h0m0s".`,
+		},
+		{
+			name: "oneof",
+			args: []string{"--format=toml"},
+			v:    &bindOpts{},
+			exp:  `bad usage: invalid argument "toml" for "-f, --format" flag: expected one of json, yaml`,
+		},
+	}
+	for _, tc := range tca {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			o := xmain.NewOpts(xos.NewEnv(tc.environ), tc.args)
+			err := o.Bind(tc.v)
+			if err == nil {
+				err = o.Parse()
+			}
+			if tc.exp == "" {
+				assert.Success(t, err)
+				return
+			}
+			assert.ErrorString(t, err, tc.exp)
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package xmain
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	"oss.terrastruct.com/util-go/go2"
+)
+
+// Command is a subcommand of a program like serve in `tool serve`.
+//
+// Pass the Main method of the root command to xmain.Main to dispatch to the
+// command named by the arguments:
+//
+//	root := &xmain.Command{
+//		PersistentFlags: func(o *xmain.Opts) error { ... },
+//		Commands: []*xmain.Command{
+//			{Name: "serve", Short: "Serve diagrams over HTTP", Run: serveRun},
+//			{Name: "fmt", Short: "Format files", Run: fmtRun},
+//		},
+//	}
+//	xmain.Main(root.Main)
+//
+// Commands declare their own flags in Flags and then parse ms.Opts.Args in Run
+// with ms.Opts.Parse just like a RunFunc passed directly to xmain.Main. Return
+// pflag.ErrHelp to have the help of the command printed.
+type Command struct {
+	// Name is what the command is invoked as. The root command defaults to the
+	// program name.
+	Name string
+	// Aliases are alternate names for the command.
+	Aliases []string
+	// Usage is the synopsis of the command's arguments. e.g. "[flags] <file>"
+	Usage string
+	// Short is a one line description shown in the parent's list of commands.
+	Short string
+	// Long is the description shown in the command's help. Defaults to Short.
+	Long string
+	// Examples are shown at the end of the command's help.
+	Examples string
+	// Hidden commands are left out of help and suggestions but may still be run.
+	Hidden bool
+
+	// PersistentFlags declares flags on o that are shared by the command and all of
+	// its subcommands. The flags may be passed before or after the subcommand name.
+	PersistentFlags func(o *Opts) error
+
+	// Flags declares the flags of the command on o before Run is called. Shell
+	// completion calls it without Run so flags declared in Run are not completed.
+	Flags func(o *Opts) error
+
+	// Run runs the command. A command with subcommands runs Run when the
+	// arguments do not name a subcommand.
+	Run RunFunc
+
+	// Commands are the subcommands.
+	//
+	// The root command also gets a completion command to generate shell completion
+	// scripts. See Completion.
+	Commands []*Command
+
+	// CompleteArgs completes the positional arguments of the command.
+	// See Opts.Complete for completing flag values.
+	CompleteArgs CompleteFunc
+
+	parent *Command
+}
+
+// Main implements RunFunc to run the command named by ms.Opts.Args.
+//
+// ms.Cmd is set to the command that is run and ms.Opts to its options containing
+// the persistent flags of all of its parents.
+//
+// An unknown command is a UsageError with suggestions of similarly named commands.
+func (c *Command) Main(ctx context.Context, ms *State) error {
+	if c.Name == "" {
+		c.Name = filepath.Base(ms.Name)
+	}
+	c.addBuiltins()
+
+	cmd, err := c.resolve(ms)
+	if err != nil {
+		return err
+	}
+	if cmd.Flags != nil {
+		err = cmd.Flags(ms.Opts)
+		if err != nil {
+			return err
+		}
+	}
+	if cmd.Run != nil {
+		return cmd.Run(ctx, ms)
+	}
+	err = ms.Opts.Parse()
+	if err != nil {
+		return err
+	}
+	return UsageErrorf("missing command for %q", cmd.Path())
+}
+
+// resolve returns the command named by ms.Opts.Args. ms.Cmd and ms.Opts are set
+// to the command and its options with the persistent flags of it and its parents
+// declared.
+func (c *Command) resolve(ms *State) (*Command, error) {
+	ms.Cmd = c
+	if c.PersistentFlags != nil {
+		err := c.PersistentFlags(ms.Opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	i := c.findCommandArg(ms.Opts)
+	if i < 0 {
+		return c, nil
+	}
+	name := ms.Opts.Args[i]
+	sub := c.lookup(name)
+	if sub == nil {
+		if c.Run == nil {
+			return nil, c.unknownCommand(name)
+		}
+		return c, nil
+	}
+	sub.parent = c
+	args := make([]string, 0, len(ms.Opts.Args)-1)
+	args = append(args, ms.Opts.Args[:i]...)
+	args = append(args, ms.Opts.Args[i+1:]...)
+	ms.Opts = ms.Opts.child(args)
+	return sub.resolve(ms)
+}
+
+// Path returns the names of the command and its parents joined with spaces.
+// e.g. "tool serve"
+func (c *Command) Path() string {
+	if c.parent == nil {
+		return c.Name
+	}
+	return c.parent.Path() + " " + c.Name
+}
+
+func (c *Command) lookup(name string) *Command {
+	for _, sub := range c.Commands {
+		if sub.Name == name {
+			return sub
+		}
+		for _, a := range sub.Aliases {
+			if a == name {
+				return sub
+			}
+		}
+	}
+	return nil
+}
+
+// findCommandArg returns the index of the first positional argument which may be the
+// name of a subcommand. It skips the values of the flags in o that require one.
+func (c *Command) findCommandArg(o *Opts) int {
+	if len(c.Commands) == 0 {
+		return -1
+	}
+	for i := 0; i < len(o.Args); i++ {
+		arg := o.Args[i]
+		switch {
+		case arg == "--":
+			return -1
+		case arg == "-" || !strings.HasPrefix(arg, "-"):
+			return i
+		case strings.Contains(arg, "="):
+			continue
+		}
+
+		if flagTakesValue(o.Flags, arg) {
+			i++
+		}
+	}
+	return -1
+}
+
+// flagTakesValue reports whether the flag argument arg is followed by its value as
+// the next argument.
+func flagTakesValue(flags *pflag.FlagSet, arg string) bool {
+	if strings.HasPrefix(arg, "--") {
+		f := flags.Lookup(arg[2:])
+		return f != nil && f.NoOptDefVal == ""
+	}
+	// In a group of shorthands like -vf, a shorthand that takes a value consumes the
+	// rest of the group or the next argument if it is last.
+	for i := 1; i < len(arg); i++ {
+		f := flags.ShorthandLookup(arg[i : i+1])
+		if f != nil && f.NoOptDefVal == "" {
+			return i == len(arg)-1
+		}
+	}
+	return false
+}
+
+func (c *Command) unknownCommand(name string) error {
+	msg := fmt.Sprintf("unknown command %q for %q", name, c.Path())
+	var suggestions []string
+	for _, sub := range c.Commands {
+		if sub.Hidden {
+			continue
+		}
+		if strings.HasPrefix(sub.Name, name) || levenshtein(name, sub.Name) <= 2 {
+			suggestions = append(suggestions, sub.Name)
+		}
+	}
+	if len(suggestions) > 0 {
+		msg += fmt.Sprintf(". Did you mean %s?", strings.Join(suggestions, " or "))
+	}
+	return UsageError{Message: msg}
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = go2.Min(go2.Min(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
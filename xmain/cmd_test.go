@@ -0,0 +1,227 @@
+package xmain_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"oss.terrastruct.com/util-go/assert"
+	"oss.terrastruct.com/util-go/xmain"
+	"oss.terrastruct.com/util-go/xos"
+)
+
+func testCommand() *xmain.Command {
+	var verbose *bool
+	var port *int64
+	var message *string
+	return &xmain.Command{
+		Long: "tool does things.",
+		PersistentFlags: func(o *xmain.Opts) (err error) {
+			verbose, err = o.Bool("TOOL_VERBOSE", "verbose", "v", false, "log more")
+			return err
+		},
+		Commands: []*xmain.Command{
+			{
+				Name:    "serve",
+				Aliases: []string{"s"},
+				Short:   "Serve over HTTP",
+				Usage:   "[flags] <file>",
+				Examples: `tool serve x.d2
+tool serve -p 80 x.d2`,
+				CompleteArgs: xmain.CompleteFiles(".d2"),
+				Flags: func(o *xmain.Opts) (err error) {
+					port, err = o.Int64("", "port", "p", 8080, "port to listen on")
+					if err != nil {
+						return err
+					}
+					o.Complete("port", xmain.CompleteValues("80", "8080"))
+					return nil
+				},
+				Run: func(ctx context.Context, ms *xmain.State) error {
+					err := ms.Opts.Parse()
+					if err != nil {
+						return err
+					}
+					fmt.Fprintf(ms.Stdout, "%s verbose=%t port=%d args=%v", ms.Cmd.Path(), *verbose, *port, ms.Opts.Flags.Args())
+					return nil
+				},
+			},
+			{
+				Name:  "db",
+				Short: "Manage the database",
+				Commands: []*xmain.Command{
+					{
+						Name:  "migrate",
+						Short: "Run migrations",
+						Flags: func(o *xmain.Opts) error {
+							message = o.String("", "message", "m", "", "migration message")
+							return nil
+						},
+						Run: func(ctx context.Context, ms *xmain.State) error {
+							err := ms.Opts.Parse()
+							if err != nil {
+								return err
+							}
+							fmt.Fprintf(ms.Stdout, "%s verbose=%t message=%q", ms.Cmd.Path(), *verbose, *message)
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:   "secret",
+				Hidden: true,
+				Run: func(ctx context.Context, ms *xmain.State) error {
+					fmt.Fprint(ms.Stdout, "shh")
+					return nil
+				},
+			},
+			{
+				Name:   "pin",
+				Hidden: true,
+				Run: func(ctx context.Context, ms *xmain.State) error {
+					version := ms.Opts.String("", "version", "", "", "version to pin")
+					err := ms.Opts.Parse()
+					if err != nil {
+						return err
+					}
+					fmt.Fprintf(ms.Stdout, "pinned %s", *version)
+					return nil
+				},
+			},
+		},
+	}
+}
+
+func TestCommand(t *testing.T) {
+	t.Parallel()
+
+	tca := []struct {
+		name   string
+		args   []string
+		exp    string
+		expErr string
+		// expPrefix checks only that the output starts with exp.
+		expPrefix bool
+	}{
+		{
+			name: "persistent",
+			args: []string{"tool", "-v", "serve", "--port", "80", "x.d2"},
+			exp:  "tool serve verbose=true port=80 args=[x.d2]",
+		},
+		{
+			name: "alias",
+			args: []string{"tool", "s", "--verbose"},
+			exp:  "tool serve verbose=true port=8080 args=[]",
+		},
+		{
+			name: "nested",
+			args: []string{"tool", "db", "-v", "migrate"},
+			exp:  `tool db migrate verbose=true message=""`,
+		},
+		{
+			name: "hidden",
+			args: []string{"tool", "secret"},
+			exp:  "shh",
+		},
+		{
+			name:   "unknown",
+			args:   []string{"tool", "srve"},
+			expErr: `failed to wait xmain test: tool: bad usage: unknown command "srve" for "tool". Did you mean serve?`,
+		},
+		{
+			name:   "missing",
+			args:   []string{"tool", "db"},
+			expErr: `failed to wait xmain test: tool: bad usage: missing command for "tool db"`,
+		},
+		{
+			name:      "version",
+			args:      []string{"tool", "serve", "--version"},
+			exp:       "tool ",
+			expPrefix: true,
+		},
+		{
+			name: "versionValue",
+			args: []string{"tool", "db", "migrate", "-m", "--version"},
+			exp:  `tool db migrate verbose=false message="--version"`,
+		},
+		{
+			name: "versionDeclared",
+			args: []string{"tool", "pin", "--version", "v1.2.0"},
+			exp:  "pinned v1.2.0",
+		},
+		{
+			name: "help",
+			args: []string{"tool", "--help"},
+			exp: `Usage:
+  tool <command> [flags]
+
+tool does things.
+
+Commands:
+  completion  Print a shell completion script
+  db          Manage the database
+  serve (s)   Serve over HTTP
+
+Flags:
+  -v, --verbose   $TOOL_VERBOSE  log more (default false)
+  -h, --help                     print usage and exit (default false)
+      --version                  print version and exit (default false)
+
+Run 'tool <command> --help' for help on a command.
+`,
+		},
+		{
+			name: "subcommandHelp",
+			args: []string{"tool", "serve", "-h"},
+			exp: `Usage:
+  tool serve [flags] <file>
+
+Serve over HTTP
+
+Flags:
+  -v, --verbose    $TOOL_VERBOSE  log more (default false)
+  -p, --port int                  port to listen on (default 8080)
+  -h, --help                      print usage and exit (default false)
+      --version                   print version and exit (default false)
+
+Examples:
+  tool serve x.d2
+  tool serve -p 80 x.d2
+`,
+		},
+	}
+
+	ctx := context.Background()
+	for _, tc := range tca {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			stdout := &strings.Builder{}
+			ts := &xmain.TestState{
+				Run:    testCommand().Main,
+				Env:    xos.NewEnv(nil),
+				Args:   tc.args,
+				Stdout: stdout,
+			}
+			ts.Start(t, ctx)
+			defer ts.Cleanup(t)
+
+			err := ts.Wait(ctx)
+			if tc.expErr != "" {
+				assert.ErrorString(t, err, tc.expErr)
+				return
+			}
+			assert.Success(t, err)
+			if tc.expPrefix {
+				if !strings.HasPrefix(stdout.String(), tc.exp) {
+					t.Fatalf("expected output to start with %q: %q", tc.exp, stdout.String())
+				}
+				return
+			}
+			assert.String(t, tc.exp, stdout.String())
+		})
+	}
+}
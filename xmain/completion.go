@@ -0,0 +1,276 @@
+package xmain
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	"oss.terrastruct.com/util-go/go2"
+)
+
+// CompleteFunc returns the completions of the partially typed argument toComplete.
+// Completions that do not start with toComplete are ignored. Completions ending
+// in / are completed without a trailing space so that completion may continue into
+// a directory.
+type CompleteFunc func(ctx context.Context, ms *State, toComplete string) []string
+
+// Complete sets fn to complete the values of flag.
+func (o *Opts) Complete(flag string, fn CompleteFunc) {
+	o.completions[flag] = fn
+}
+
+// CompleteValues returns a CompleteFunc completing a fixed set of values.
+func CompleteValues(values ...string) CompleteFunc {
+	return func(ctx context.Context, ms *State, toComplete string) []string {
+		return values
+	}
+}
+
+// CompleteFiles returns a CompleteFunc completing paths to files with one of exts
+// like ".d2" or any file if exts is empty.
+func CompleteFiles(exts ...string) CompleteFunc {
+	return func(ctx context.Context, ms *State, toComplete string) []string {
+		dir, base := filepath.Split(toComplete)
+		entries, err := os.ReadDir(ms.AbsPath(dir))
+		if err != nil {
+			return nil
+		}
+
+		var completions []string
+		for _, e := range entries {
+			name := e.Name()
+			if !strings.HasPrefix(name, base) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(base, ".")) {
+				continue
+			}
+			if e.IsDir() {
+				completions = append(completions, dir+name+"/")
+				continue
+			}
+			if len(exts) == 0 || go2.Contains(exts, filepath.Ext(name)) {
+				completions = append(completions, dir+name)
+			}
+		}
+		return completions
+	}
+}
+
+// addBuiltins adds the completion commands to the root command c.
+func (c *Command) addBuiltins() {
+	if c.lookup("completion") == nil {
+		c.Commands = append(c.Commands, &Command{
+			Name:  "completion",
+			Usage: "<bash|zsh|fish>",
+			Short: "Print a shell completion script",
+			Long: fmt.Sprintf(`Print a shell completion script for %[1]s.
+
+To load completions in the current shell:
+
+  source <(%[1]s completion bash)
+  source <(%[1]s completion zsh)
+  %[1]s completion fish | source`, c.Name),
+			Run:          c.completionRun,
+			CompleteArgs: CompleteValues("bash", "zsh", "fish"),
+		})
+	}
+	if c.lookup("__complete") == nil {
+		c.Commands = append(c.Commands, &Command{
+			Name:   "__complete",
+			Hidden: true,
+			Run:    c.completeRun,
+		})
+	}
+}
+
+func (c *Command) completionRun(ctx context.Context, ms *State) error {
+	err := ms.Opts.Flags.Parse(ms.Opts.Args)
+	if err != nil {
+		return err
+	}
+	if ms.Opts.Flags.NArg() != 1 {
+		return UsageErrorf("expected one shell argument")
+	}
+	return Completion(ms.Stdout, c.Name, ms.Opts.Flags.Arg(0))
+}
+
+// Completion writes the completion script for shell to w.
+// shell is one of bash, zsh or fish.
+//
+// The script runs the hidden command `name __complete <args>` to get completions.
+func Completion(w io.Writer, name, shell string) error {
+	var script string
+	switch shell {
+	case "bash":
+		script = bashCompletion
+	case "zsh":
+		script = zshCompletion
+	case "fish":
+		script = fishCompletion
+	default:
+		return UsageErrorf("unsupported shell %q: expected bash, zsh or fish", shell)
+	}
+	r := strings.NewReplacer("NAME", name, "FUNC", funcNameRegex.ReplaceAllString(name, "_"))
+	_, err := io.WriteString(w, r.Replace(script))
+	return err
+}
+
+var funcNameRegex = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// completeRun prints the completions of the last argument given the arguments
+// before it, one per line.
+func (c *Command) completeRun(ctx context.Context, ms *State) error {
+	words := ms.Opts.Args
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	toComplete := words[len(words)-1]
+
+	// Not ms.Opts.child as the persistent flags are declared again by complete.
+	o := NewOpts(ms.Env, words[:len(words)-1])
+	o.Width = ms.Opts.Width
+	o.pwd = ms.Opts.pwd
+	ms.Opts = o
+	completions := c.complete(ctx, ms, toComplete)
+
+	sort.Strings(completions)
+	for _, s := range completions {
+		if strings.HasPrefix(s, toComplete) {
+			fmt.Fprintln(ms.Stdout, s)
+		}
+	}
+	return nil
+}
+
+func (c *Command) complete(ctx context.Context, ms *State, toComplete string) []string {
+	cmd, err := c.resolve(ms)
+	if err != nil {
+		return nil
+	}
+	if cmd.Flags != nil && cmd.Flags(ms.Opts) != nil {
+		return nil
+	}
+	o := ms.Opts
+
+	completeFlag := func(name, prefix, toComplete string) []string {
+		fn := o.completions[name]
+		if fn == nil {
+			return nil
+		}
+		var completions []string
+		for _, s := range fn(ctx, ms, toComplete) {
+			completions = append(completions, prefix+s)
+		}
+		return completions
+	}
+
+	// The value of the previous flag.
+	if len(o.Args) > 0 {
+		prev := o.Args[len(o.Args)-1]
+		if strings.HasPrefix(prev, "-") && !strings.Contains(prev, "=") && flagTakesValue(o.Flags, prev) {
+			f := o.Flags.Lookup(strings.TrimPrefix(prev, "--"))
+			if !strings.HasPrefix(prev, "--") {
+				f = o.Flags.ShorthandLookup(prev[len(prev)-1:])
+			}
+			if f == nil {
+				return nil
+			}
+			return completeFlag(f.Name, "", toComplete)
+		}
+	}
+
+	if strings.HasPrefix(toComplete, "-") {
+		if i := strings.Index(toComplete, "="); i >= 0 && strings.HasPrefix(toComplete, "--") {
+			return completeFlag(toComplete[2:i], toComplete[:i+1], toComplete[i+1:])
+		}
+		var completions []string
+		o.Flags.VisitAll(func(f *pflag.Flag) {
+			if !f.Hidden {
+				completions = append(completions, "--"+f.Name)
+			}
+		})
+		for _, name := range []string{"help", "version"} {
+			if o.Flags.Lookup(name) == nil {
+				completions = append(completions, "--"+name)
+			}
+		}
+		return completions
+	}
+
+	var completions []string
+	for _, sub := range cmd.Commands {
+		if !sub.Hidden {
+			completions = append(completions, sub.Name)
+		}
+	}
+	if cmd.CompleteArgs != nil {
+		completions = append(completions, cmd.CompleteArgs(ctx, ms, toComplete)...)
+	}
+	return completions
+}
+
+const bashCompletion = `# bash completion for NAME
+# source <(NAME completion bash)
+
+_FUNC_complete() {
+	local line="${COMP_LINE:0:COMP_POINT}"
+	local -a words
+	read -r -a words <<<"$line"
+	if [[ "$line" == *" " ]]; then
+		words+=("")
+	fi
+
+	# bash splits words on = so only the part after it is replaced.
+	local cur="${COMP_WORDS[COMP_CWORD]}"
+	local prefix="${words[${#words[@]}-1]%"$cur"}"
+
+	local IFS=$'\n'
+	COMPREPLY=($("${words[0]}" __complete "${words[@]:1}" 2>/dev/null))
+	COMPREPLY=("${COMPREPLY[@]#"$prefix"}")
+	if [[ ${#COMPREPLY[@]} -eq 1 && "${COMPREPLY[0]}" == */ ]]; then
+		compopt -o nospace
+	fi
+}
+
+complete -F _FUNC_complete NAME
+`
+
+const zshCompletion = `#compdef NAME
+# source <(NAME completion zsh)
+
+_FUNC_complete() {
+	local -a completions dirs
+	local c
+	for c in "${(@f)$("${words[1]}" __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}"; do
+		if [[ "$c" == */ ]]; then
+			dirs+=("$c")
+		elif [[ -n "$c" ]]; then
+			completions+=("$c")
+		fi
+	done
+	compadd -- "${completions[@]}"
+	compadd -S '' -- "${dirs[@]}"
+}
+
+if [[ "${funcstack[1]}" == "_FUNC_complete" ]]; then
+	_FUNC_complete "$@"
+else
+	compdef _FUNC_complete NAME
+fi
+`
+
+const fishCompletion = `# fish completion for NAME
+# NAME completion fish | source
+
+function __FUNC_complete
+	set -l args (commandline -opc)
+	$args[1] __complete $args[2..-1] (commandline -ct) 2>/dev/null
+end
+
+complete -c NAME -f -a '(__FUNC_complete)'
+`
@@ -0,0 +1,210 @@
+package xmain_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"oss.terrastruct.com/util-go/assert"
+	"oss.terrastruct.com/util-go/xmain"
+	"oss.terrastruct.com/util-go/xos"
+)
+
+func TestCompletion(t *testing.T) {
+	t.Parallel()
+
+	pwd := t.TempDir()
+	for _, fp := range []string{"a.d2", "b.txt", "dir/c.d2"} {
+		err := os.MkdirAll(filepath.Join(pwd, filepath.Dir(fp)), 0755)
+		assert.Success(t, err)
+		err = os.WriteFile(filepath.Join(pwd, fp), nil, 0644)
+		assert.Success(t, err)
+	}
+
+	tca := []struct {
+		name string
+		args []string
+		exp  string
+	}{
+		{
+			name: "commands",
+			args: []string{"__complete", ""},
+			exp:  "completion\ndb\nserve\n",
+		},
+		{
+			name: "nested",
+			args: []string{"__complete", "-v", "db", "m"},
+			exp:  "migrate\n",
+		},
+		{
+			name: "flags",
+			args: []string{"__complete", "serve", "--"},
+			exp:  "--help\n--port\n--verbose\n--version\n",
+		},
+		{
+			name: "flagValue",
+			args: []string{"__complete", "s", "-p", ""},
+			exp:  "80\n8080\n",
+		},
+		{
+			name: "flagValueEquals",
+			args: []string{"__complete", "serve", "--port=8"},
+			exp:  "--port=80\n--port=8080\n",
+		},
+		{
+			name: "files",
+			args: []string{"__complete", "serve", "-v", ""},
+			exp:  "a.d2\ndir/\n",
+		},
+		{
+			name: "filesDir",
+			args: []string{"__complete", "serve", "dir/"},
+			exp:  "dir/c.d2\n",
+		},
+		{
+			name: "shells",
+			args: []string{"__complete", "completion", "z"},
+			exp:  "zsh\n",
+		},
+	}
+
+	ctx := context.Background()
+	for _, tc := range tca {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			stdout := &strings.Builder{}
+			ts := &xmain.TestState{
+				Run:    testCommand().Main,
+				Env:    xos.NewEnv(nil),
+				Args:   append([]string{"tool"}, tc.args...),
+				PWD:    pwd,
+				Stdout: stdout,
+			}
+			ts.Start(t, ctx)
+			defer ts.Cleanup(t)
+
+			err := ts.Wait(ctx)
+			assert.Success(t, err)
+			assert.String(t, tc.exp, stdout.String())
+		})
+	}
+
+	t.Run("bash", func(t *testing.T) {
+		t.Parallel()
+
+		stdout := &strings.Builder{}
+		ts := &xmain.TestState{
+			Run:    testCommand().Main,
+			Env:    xos.NewEnv(nil),
+			Args:   []string{"tool", "completion", "bash"},
+			Stdout: stdout,
+		}
+		ts.Start(t, ctx)
+		defer ts.Cleanup(t)
+
+		err := ts.Wait(ctx)
+		assert.Success(t, err)
+		if !strings.Contains(stdout.String(), "complete -F _tool_complete tool\n") {
+			t.Fatalf("unexpected bash completion: %s", stdout)
+		}
+
+		if _, err := exec.LookPath("bash"); err == nil {
+			cmd := exec.Command("bash", "-n")
+			cmd.Stdin = strings.NewReader(stdout.String())
+			out, err := cmd.CombinedOutput()
+			if err != nil {
+				t.Fatalf("invalid bash completion: %v: %s", err, out)
+			}
+		}
+	})
+}
+
+func TestCompletionSkipsRun(t *testing.T) {
+	t.Parallel()
+
+	var ran int32
+	root := &xmain.Command{
+		Commands: []*xmain.Command{
+			{
+				Name: "deploy",
+				Flags: func(o *xmain.Opts) error {
+					o.String("", "env", "", "", "environment to deploy to")
+					o.Complete("env", xmain.CompleteValues("prod", "staging"))
+					return nil
+				},
+				Run: func(ctx context.Context, ms *xmain.State) error {
+					atomic.StoreInt32(&ran, 1)
+					return nil
+				},
+			},
+		},
+	}
+
+	ctx := context.Background()
+	for _, args := range [][]string{
+		{"__complete", "deploy", "--"},
+		{"__complete", "deploy", "--env", ""},
+	} {
+		stdout := &strings.Builder{}
+		ts := &xmain.TestState{
+			Run:    root.Main,
+			Env:    xos.NewEnv(nil),
+			Args:   append([]string{"tool"}, args...),
+			Stdout: stdout,
+		}
+		ts.Start(t, ctx)
+		err := ts.Wait(ctx)
+		ts.Cleanup(t)
+		assert.Success(t, err)
+		if stdout.Len() == 0 {
+			t.Fatalf("expected completions for %q", args)
+		}
+	}
+	if atomic.LoadInt32(&ran) != 0 {
+		t.Fatal("deploy ran during completion")
+	}
+}
+
+func TestCompletionPWD(t *testing.T) {
+	t.Parallel()
+
+	var config *string
+	root := &xmain.Command{
+		Commands: []*xmain.Command{
+			{
+				Name: "deploy",
+				Flags: func(o *xmain.Opts) error {
+					config = o.Path("", "config", "", "deploy.yaml", "")
+					o.Complete("env", func(ctx context.Context, ms *xmain.State, toComplete string) []string {
+						return []string{*config}
+					})
+					o.String("", "env", "", "", "")
+					return nil
+				},
+			},
+		},
+	}
+
+	pwd := t.TempDir()
+	stdout := &strings.Builder{}
+	ts := &xmain.TestState{
+		Run:    root.Main,
+		Env:    xos.NewEnv(nil),
+		Args:   []string{"tool", "__complete", "deploy", "--env", ""},
+		PWD:    pwd,
+		Stdout: stdout,
+	}
+	ctx := context.Background()
+	ts.Start(t, ctx)
+	defer ts.Cleanup(t)
+
+	err := ts.Wait(ctx)
+	assert.Success(t, err)
+	assert.String(t, filepath.Join(pwd, "deploy.yaml")+"\n", stdout.String())
+}
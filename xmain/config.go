@@ -0,0 +1,191 @@
+package xmain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFormats maps config file extensions to functions that decode them into a
+// map[string]interface{}. JSON, TOML and YAML are supported out of the box.
+// Register others like:
+//
+//	xmain.ConfigFormats[".hcl"] = unmarshalHCL
+var ConfigFormats = map[string]func([]byte, interface{}) error{
+	".json": unmarshalJSON,
+	".toml": toml.Unmarshal,
+	".yaml": yaml.Unmarshal,
+	".yml":  yaml.Unmarshal,
+}
+
+func unmarshalJSON(b []byte, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(b))
+	// Keep int64 values exact.
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+// LoadConfig declares the --config flag and loads the config file it names.
+//
+// Call it before declaring any other flags so that they take their defaults from
+// the config file. Values are resolved with the precedence flag > environment
+// variable > config file > default.
+//
+// The path to the config file is taken from --config, $<NAME>_CONFIG or else the
+// first of $XDG_CONFIG_HOME/<name>/config.<ext> that exists for each extension in
+// ConfigFormats. $XDG_CONFIG_HOME defaults to ~/.config. It is not an error for
+// the default config file to not exist. A relative path is relative to the PWD.
+//
+// A flag is set from the config key of the same name. Nested objects are addressed
+// with dots, e.g. server.port, and arrays are joined with commas. Use ConfigKey to
+// read a flag from a different key.
+func (o *Opts) LoadConfig(name string) error {
+	envKey := strings.ToUpper(funcNameRegex.ReplaceAllString(name, "_")) + "_CONFIG"
+	fp := o.String(envKey, "config", "", "", "path to the config file")
+	if v, ok := o.argValue("config"); ok {
+		*fp = v
+	}
+
+	explicit := *fp != ""
+	if explicit && o.pwd != "" {
+		*fp = absPath(o.pwd, *fp)
+	}
+	if !explicit {
+		*fp = o.defaultConfigPath(name)
+		if *fp == "" {
+			return nil
+		}
+	}
+
+	b, err := os.ReadFile(*fp)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	decode, ok := ConfigFormats[filepath.Ext(*fp)]
+	if !ok {
+		return UsageErrorf("unsupported config file format %q: expected one of %s", filepath.Ext(*fp), configExts())
+	}
+	var m map[string]interface{}
+	err = decode(b, &m)
+	if err != nil {
+		return fmt.Errorf("failed to decode config %s: %w", *fp, err)
+	}
+
+	o.config = make(map[string]string)
+	flattenConfig(o.config, "", m)
+	o.configPath = *fp
+	if !explicit {
+		o.Flags.Lookup("config").DefValue = *fp
+	}
+	return nil
+}
+
+// ConfigKey sets the config key flag is read from when declared.
+// It must be called before declaring flag.
+func (o *Opts) ConfigKey(flag, key string) {
+	o.configKeys[flag] = key
+}
+
+func (o *Opts) getConfig(flag string) (v, key string, ok bool) {
+	key = flag
+	if k, ok := o.configKeys[flag]; ok {
+		key = k
+	}
+	v, ok = o.config[key]
+	return v, key, ok
+}
+
+// argValue returns the value of the flag named name in o.Args before they are
+// parsed.
+func (o *Opts) argValue(name string) (string, bool) {
+	for i, arg := range o.Args {
+		switch {
+		case arg == "--":
+			return "", false
+		case arg == "--"+name && i+1 < len(o.Args):
+			return o.Args[i+1], true
+		case strings.HasPrefix(arg, "--"+name+"="):
+			return strings.TrimPrefix(arg, "--"+name+"="), true
+		}
+	}
+	return "", false
+}
+
+func (o *Opts) defaultConfigPath(name string) string {
+	dir := o.env.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home := o.env.Getenv("HOME")
+		if home == "" {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	for _, ext := range configExts() {
+		fp := filepath.Join(dir, name, "config"+ext)
+		if _, err := os.Stat(fp); err == nil {
+			return fp
+		}
+	}
+	return ""
+}
+
+func configExts() []string {
+	exts := make([]string, 0, len(ConfigFormats))
+	for ext := range ConfigFormats {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+func flattenConfig(dst map[string]string, prefix string, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, v2 := range v {
+			flattenConfig(dst, prefix+k+".", v2)
+		}
+		return
+	case map[interface{}]interface{}:
+		for k, v2 := range v {
+			flattenConfig(dst, prefix+fmt.Sprint(k)+".", v2)
+		}
+		return
+	}
+
+	key := strings.TrimSuffix(prefix, ".")
+	switch v := v.(type) {
+	case nil:
+	case []interface{}:
+		els := make([]string, len(v))
+		for i, el := range v {
+			els[i] = configString(el)
+		}
+		dst[key] = strings.Join(els, ",")
+	default:
+		dst[key] = configString(v)
+	}
+}
+
+func configString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
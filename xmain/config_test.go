@@ -0,0 +1,179 @@
+package xmain_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"oss.terrastruct.com/util-go/assert"
+	"oss.terrastruct.com/util-go/xmain"
+	"oss.terrastruct.com/util-go/xos"
+)
+
+func TestConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(dir, "tool"), 0755)
+	assert.Success(t, err)
+	err = os.WriteFile(filepath.Join(dir, "tool", "config.json"), []byte(`{
+  "name": "config",
+  "port": 9999999999,
+  "verbose": true,
+  "server": {"addr": "localhost"},
+  "ids": [1, 2]
+}`), 0644)
+	assert.Success(t, err)
+
+	type values struct {
+		Name    string  `json:"name"`
+		Port    int64   `json:"port"`
+		Verbose bool    `json:"verbose"`
+		Addr    string  `json:"addr"`
+		IDs     []int64 `json:"ids"`
+	}
+	declare := func(t *testing.T, o *xmain.Opts) func() values {
+		err := o.LoadConfig("tool")
+		assert.Success(t, err)
+		name := o.String("TOOL_NAME", "name", "", "default", "")
+		port, err := o.Int64("", "port", "", 80, "")
+		assert.Success(t, err)
+		verbose, err := o.Bool("", "verbose", "", false, "")
+		assert.Success(t, err)
+		o.ConfigKey("addr", "server.addr")
+		addr := o.String("", "addr", "", "", "")
+		ids, err := o.Int64Slice("", "ids", "", nil, "")
+		assert.Success(t, err)
+
+		err = o.Flags.Parse(o.Args)
+		assert.Success(t, err)
+		return func() values {
+			return values{*name, *port, *verbose, *addr, *ids}
+		}
+	}
+
+	t.Run("config", func(t *testing.T) {
+		t.Parallel()
+
+		env := xos.NewEnv([]string{"XDG_CONFIG_HOME=" + dir})
+		got := declare(t, xmain.NewOpts(env, nil))()
+		assert.StringJSON(t, `{
+  "name": "config",
+  "port": 9999999999,
+  "verbose": true,
+  "addr": "localhost",
+  "ids": [
+    1,
+    2
+  ]
+}`, got)
+	})
+
+	t.Run("precedence", func(t *testing.T) {
+		t.Parallel()
+
+		env := xos.NewEnv([]string{"XDG_CONFIG_HOME=" + dir, "TOOL_NAME=env", "DEBUG=1"})
+		o := xmain.NewOpts(env, []string{"--port", "8080"})
+		got := declare(t, o)()
+		assert.Equal(t, "env", got.Name)
+		assert.Equal(t, int64(8080), got.Port)
+
+		fp := filepath.Join(dir, "tool", "config.json")
+		assert.String(t, `      --config string    $TOOL_CONFIG  path to the config file (default "`+fp+`") (from default)
+      --name string      $TOOL_NAME     (default "env") (from environment variable TOOL_NAME)
+      --port int                        (default 9999999999) (from flag)
+      --verbose                         (default true) (from config key verbose in `+fp+`)
+      --addr string                     (default "localhost") (from config key server.addr in `+fp+`)
+      --ids int64Slice                  (default [1,2]) (from config key ids in `+fp+`)
+`, o.Defaults())
+	})
+
+	t.Run("flag", func(t *testing.T) {
+		t.Parallel()
+
+		fp := filepath.Join(t.TempDir(), "tool.json")
+		err := os.WriteFile(fp, []byte(`{"name": "flag"}`), 0644)
+		assert.Success(t, err)
+
+		env := xos.NewEnv([]string{"XDG_CONFIG_HOME=" + dir})
+		got := declare(t, xmain.NewOpts(env, []string{"--config=" + fp}))()
+		assert.Equal(t, "flag", got.Name)
+		assert.Equal(t, int64(80), got.Port)
+	})
+
+	t.Run("formats", func(t *testing.T) {
+		t.Parallel()
+
+		tca := map[string]string{
+			"tool.yaml": `
+name: yaml
+port: 9999999999
+server:
+  addr: example.com
+ids: [3, 4]
+`,
+			"tool.toml": `
+name = "toml"
+port = 9999999999
+ids = [3, 4]
+
+[server]
+addr = "example.com"
+`,
+		}
+		for name, content := range tca {
+			fp := filepath.Join(t.TempDir(), name)
+			err := os.WriteFile(fp, []byte(content), 0644)
+			assert.Success(t, err)
+
+			env := xos.NewEnv(nil)
+			got := declare(t, xmain.NewOpts(env, []string{"--config", fp}))()
+			assert.Equal(t, strings.TrimPrefix(filepath.Ext(name), "."), got.Name)
+			assert.Equal(t, int64(9999999999), got.Port)
+			assert.Equal(t, "example.com", got.Addr)
+			assert.StringJSON(t, `[
+  3,
+  4
+]`, got.IDs)
+		}
+	})
+
+	t.Run("relative", func(t *testing.T) {
+		t.Parallel()
+
+		ts := &xmain.TestState{
+			Run: func(ctx context.Context, ms *xmain.State) error {
+				got := declare(t, ms.Opts)()
+				if got.Name != "config" {
+					return fmt.Errorf("expected name from config but got %q", got.Name)
+				}
+				return nil
+			},
+			Env:  xos.NewEnv(nil),
+			Args: []string{"tool", "--config", "tool/config.json"},
+			PWD:  dir,
+		}
+		ctx := context.Background()
+		ts.Start(t, ctx)
+		defer ts.Cleanup(t)
+		err := ts.Wait(ctx)
+		assert.Success(t, err)
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		t.Parallel()
+
+		env := xos.NewEnv([]string{"XDG_CONFIG_HOME=" + t.TempDir()})
+		got := declare(t, xmain.NewOpts(env, nil))()
+		assert.Equal(t, "default", got.Name)
+
+		env.Setenv("TOOL_CONFIG", filepath.Join(t.TempDir(), "nope.json"))
+		err := xmain.NewOpts(env, nil).LoadConfig("tool")
+		if err == nil {
+			t.Fatal("expected error for missing explicit config")
+		}
+	})
+}
@@ -0,0 +1,188 @@
+package xmain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/pflag"
+)
+
+// Version is printed by --version. If empty, the version of the main module from
+// the build info is used. Set it at build time with:
+//
+//	go build -ldflags "-X oss.terrastruct.com/util-go/xmain.Version=v1.0.0"
+var Version string
+
+// UsageTemplate is the text/template used by State.Usage.
+// See usageData for the fields available.
+var UsageTemplate = `Usage:
+  {{.Path}} {{.Synopsis}}
+{{- with .Description}}
+
+{{.}}
+{{- end}}
+{{- with .Commands}}
+
+Commands:
+{{- range .}}
+  {{.}}
+{{- end}}
+{{- end}}
+
+Flags:
+{{.Flags}}
+{{- with .FlagGroups}}
+Flag groups:
+{{- range .}}
+  {{.}}
+{{- end}}
+{{end}}
+{{- with .Examples}}
+Examples:
+{{.}}
+{{- end}}
+{{- if .Commands}}
+Run '{{.Path}} <command> --help' for help on a command.
+{{end}}`
+
+type usageData struct {
+	// Path is the program name followed by any subcommands. e.g. "tool serve"
+	Path string
+	// Synopsis is Command.Usage or a default like "[flags]".
+	Synopsis    string
+	Description string
+	// Commands are the formatted lines listing the visible subcommands.
+	Commands []string
+	// Flags is the table from Opts.Defaults including --help.
+	Flags string
+	// FlagGroups describe the groups declared with Opts.MutuallyExclusive and
+	// Opts.AtLeastOne.
+	FlagGroups []string
+	// Examples is Command.Examples indented.
+	Examples string
+}
+
+// Usage returns the help text for the command being run.
+//
+// It is printed to Stdout when the RunFunc returns an error wrapping pflag.ErrHelp
+// as Opts.Flags.Parse does when passed --help or -h.
+func (ms *State) Usage() string {
+	c := ms.Cmd
+	if c == nil {
+		c = &Command{Name: filepath.Base(ms.Name)}
+	}
+
+	d := usageData{
+		Path:        c.Path(),
+		Synopsis:    c.Usage,
+		Description: strings.TrimSpace(c.Long),
+		Examples:    indent(strings.Trim(c.Examples, "\n")),
+	}
+	if d.Synopsis == "" {
+		d.Synopsis = "[flags]"
+		if len(c.Commands) > 0 && c.Run == nil {
+			d.Synopsis = "<command> [flags]"
+		}
+	}
+	if d.Description == "" {
+		d.Description = strings.TrimSpace(c.Short)
+	}
+
+	var commands [][2]string
+	width := 0
+	for _, sub := range c.Commands {
+		if sub.Hidden {
+			continue
+		}
+		name := sub.Name
+		if len(sub.Aliases) > 0 {
+			name += " (" + strings.Join(sub.Aliases, ", ") + ")"
+		}
+		if len(name) > width {
+			width = len(name)
+		}
+		commands = append(commands, [2]string{name, sub.Short})
+	}
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i][0] < commands[j][0]
+	})
+	for _, cmd := range commands {
+		d.Commands = append(d.Commands, fmt.Sprintf("%-*s  %s", width, cmd[0], cmd[1]))
+	}
+
+	// --help is declared on a copy for programs that parse with Opts.Flags.Parse
+	// rather than Opts.Parse.
+	o := ms.Opts.child(nil)
+	if o.Flags.Lookup("help") == nil {
+		o.builtinHelp()
+	}
+	d.Flags = o.Defaults()
+	for _, g := range o.groups {
+		d.FlagGroups = append(d.FlagGroups, g.String())
+	}
+
+	var sb strings.Builder
+	err := template.Must(template.New("usage").Parse(UsageTemplate)).Execute(&sb, d)
+	if err != nil {
+		return fmt.Sprintf("failed to execute usage template: %v\n", err)
+	}
+	return sb.String()
+}
+
+func indent(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "  " + strings.ReplaceAll(s, "\n", "\n  ") + "\n"
+}
+
+// runBuiltin runs run handling pflag.ErrHelp and the --version of Opts.Parse.
+func (ms *State) runBuiltin(ctx context.Context, run RunFunc) error {
+	err := run(ctx, ms)
+	switch {
+	case errors.Is(err, pflag.ErrHelp):
+		_, err = fmt.Fprint(ms.Stdout, ms.Usage())
+	case errors.Is(err, errVersion):
+		_, err = fmt.Fprintln(ms.Stdout, ms.version())
+	}
+	return err
+}
+
+// version returns the program name followed by Version or the build info.
+func (ms *State) version() string {
+	name := filepath.Base(ms.Name)
+	if Version != "" {
+		return name + " " + Version
+	}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return name + " unknown"
+	}
+	v := name + " " + bi.Main.Version
+	var rev string
+	var modified bool
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			rev = s.Value
+		case "vcs.modified":
+			modified = s.Value == "true"
+		}
+	}
+	if rev != "" {
+		if len(rev) > 12 {
+			rev = rev[:12]
+		}
+		if modified {
+			rev += "-dirty"
+		}
+		v += " " + rev
+	}
+	return v
+}
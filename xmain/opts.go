@@ -4,12 +4,17 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 
+	"oss.terrastruct.com/util-go/go2"
 	"oss.terrastruct.com/util-go/xos"
+	"oss.terrastruct.com/util-go/xterm"
 )
 
 type Opts struct {
@@ -17,7 +22,29 @@ type Opts struct {
 	Flags *pflag.FlagSet
 	env   *xos.Env
 
-	flagEnv map[string]string
+	// Width is the terminal width Defaults wraps usage to. 0 disables wrapping.
+	// Main sets it to the width of Stdout if it is a terminal.
+	Width int
+
+	// pwd is the directory Path resolves relative paths against.
+	pwd string
+
+	flagEnv     map[string]string
+	completions map[string]CompleteFunc
+
+	// config is the flattened config file loaded by LoadConfig.
+	config     map[string]string
+	configPath string
+	// configKeys maps flags to config keys that differ from the flag name.
+	configKeys map[string]string
+	// sources describes where the default of each flag was taken from if not the
+	// default passed to its declaration.
+	sources map[string]string
+	// validators are run by Parse with the Opts being parsed after the flags are
+	// parsed. They are passed the Opts as they are inherited by subcommands.
+	validators []func(o *Opts) error
+	required   map[string]bool
+	groups     []flagGroup
 }
 
 func NewOpts(env *xos.Env, args []string) *Opts {
@@ -26,90 +53,125 @@ func NewOpts(env *xos.Env, args []string) *Opts {
 	flags.Usage = func() {}
 	flags.SetOutput(io.Discard)
 	return &Opts{
-		Args:    args,
-		Flags:   flags,
-		env:     env,
-		flagEnv: make(map[string]string),
+		Args:        args,
+		Flags:       flags,
+		env:         env,
+		flagEnv:     make(map[string]string),
+		completions: make(map[string]CompleteFunc),
+		configKeys:  make(map[string]string),
+		sources:     make(map[string]string),
+		required:    make(map[string]bool),
 	}
 }
 
+// child returns Opts for a subcommand with args that shares the flags of o.
+// Only the persistent flags are declared on o when child is called.
+func (o *Opts) child(args []string) *Opts {
+	o2 := NewOpts(o.env, args)
+	o2.Width = o.Width
+	o2.pwd = o.pwd
+	o.Flags.VisitAll(func(f *pflag.Flag) {
+		o2.Flags.AddFlag(f)
+	})
+	for k, v := range o.flagEnv {
+		o2.flagEnv[k] = v
+	}
+	for k, v := range o.completions {
+		o2.completions[k] = v
+	}
+	o2.config = o.config
+	o2.configPath = o.configPath
+	for k, v := range o.configKeys {
+		o2.configKeys[k] = v
+	}
+	for k, v := range o.sources {
+		o2.sources[k] = v
+	}
+	o2.validators = append(o2.validators, o.validators...)
+	for k, v := range o.required {
+		o2.required[k] = v
+	}
+	o2.groups = append(o2.groups, o.groups...)
+	return o2
+}
+
 // Mostly copy pasted pasted from pflag.FlagUsagesWrapped
 // with modifications for env var
 func (o *Opts) Defaults() string {
 	buf := new(bytes.Buffer)
 
-	var lines []string
+	type line struct {
+		flag  string
+		env   string
+		usage string
+	}
+	var lines []line
 
-	maxlen := 0
-	maxEnvLen := 0
+	maxFlagWidth := 0
+	maxEnvWidth := 0
 	o.Flags.VisitAll(func(flag *pflag.Flag) {
 		if flag.Hidden {
 			return
 		}
 
-		line := ""
+		l := line{}
 		if flag.Shorthand != "" && flag.ShorthandDeprecated == "" {
-			line = fmt.Sprintf("  -%s, --%s", flag.Shorthand, flag.Name)
+			l.flag = fmt.Sprintf("  -%s, --%s", flag.Shorthand, flag.Name)
 		} else {
-			line = fmt.Sprintf("      --%s", flag.Name)
+			l.flag = fmt.Sprintf("      --%s", flag.Name)
 		}
 
 		varname, usage := pflag.UnquoteUsage(flag)
 		if varname != "" {
-			line += " " + varname
+			l.flag += " " + varname
 		}
 		if flag.NoOptDefVal != "" {
 			switch flag.Value.Type() {
 			case "string":
-				line += fmt.Sprintf("[=\"%s\"]", flag.NoOptDefVal)
+				l.flag += fmt.Sprintf("[=\"%s\"]", flag.NoOptDefVal)
 			case "bool":
 				if flag.NoOptDefVal != "true" {
-					line += fmt.Sprintf("[=%s]", flag.NoOptDefVal)
+					l.flag += fmt.Sprintf("[=%s]", flag.NoOptDefVal)
 				}
 			case "count":
 				if flag.NoOptDefVal != "+1" {
-					line += fmt.Sprintf("[=%s]", flag.NoOptDefVal)
+					l.flag += fmt.Sprintf("[=%s]", flag.NoOptDefVal)
 				}
 			default:
-				line += fmt.Sprintf("[=%s]", flag.NoOptDefVal)
+				l.flag += fmt.Sprintf("[=%s]", flag.NoOptDefVal)
 			}
 		}
-
-		line += "\x00"
-
-		if len(line) > maxlen {
-			maxlen = len(line)
-		}
+		maxFlagWidth = go2.Max(maxFlagWidth, xterm.VisibleWidth(l.flag))
 
 		if e, ok := o.flagEnv[flag.Name]; ok {
-			line += fmt.Sprintf("$%s", e)
+			l.env = fmt.Sprintf("$%s", e)
 		}
+		maxEnvWidth = go2.Max(maxEnvWidth, xterm.VisibleWidth(l.env))
 
-		line += "\x01"
-
-		if len(line) > maxEnvLen {
-			maxEnvLen = len(line)
+		l.usage = usage
+		if o.required[flag.Name] {
+			l.usage += " (required)"
 		}
-
-		line += usage
 		if flag.Value.Type() == "string" {
-			line += fmt.Sprintf(" (default %q)", flag.DefValue)
+			l.usage += fmt.Sprintf(" (default %q)", flag.DefValue)
 		} else {
-			line += fmt.Sprintf(" (default %s)", flag.DefValue)
+			l.usage += fmt.Sprintf(" (default %s)", flag.DefValue)
 		}
 		if len(flag.Deprecated) != 0 {
-			line += fmt.Sprintf(" (DEPRECATED: %s)", flag.Deprecated)
+			l.usage += fmt.Sprintf(" (DEPRECATED: %s)", flag.Deprecated)
+		}
+		if o.env.Debug() {
+			l.usage += fmt.Sprintf(" (from %s)", o.source(flag))
 		}
 
-		lines = append(lines, line)
+		lines = append(lines, l)
 	})
 
-	for _, line := range lines {
-		sidx1 := strings.Index(line, "\x00")
-		sidx2 := strings.Index(line, "\x01")
-		spacing1 := strings.Repeat(" ", maxlen-sidx1)
-		spacing2 := strings.Repeat(" ", (maxEnvLen-maxlen)-sidx2+sidx1)
-		fmt.Fprintln(buf, line[:sidx1], spacing1, line[sidx1+1:sidx2], spacing2, wrap(maxEnvLen+3, 0, line[sidx2+1:]))
+	usageCol := maxFlagWidth + maxEnvWidth + 5
+	for _, l := range lines {
+		spacing1 := strings.Repeat(" ", maxFlagWidth-xterm.VisibleWidth(l.flag)+1)
+		spacing2 := strings.Repeat(" ", maxEnvWidth-xterm.VisibleWidth(l.env))
+		fmt.Fprintln(buf, l.flag, spacing1, l.env, spacing2, wrap(usageCol, o.Width, l.usage))
 	}
 
 	return buf.String()
@@ -123,58 +185,128 @@ func (o *Opts) getEnv(flag, k string) string {
 	return ""
 }
 
+// getValue returns the value of flag from the environment variable k or else from
+// the config file along with a description of where it came from.
+func (o *Opts) getValue(flag, k string) (v, src string) {
+	if env := o.getEnv(flag, k); env != "" {
+		v, src = env, "environment variable "+k
+	} else if cv, key, ok := o.getConfig(flag); ok {
+		v, src = cv, fmt.Sprintf("config key %s in %s", key, o.configPath)
+	}
+	if src != "" {
+		o.sources[flag] = src
+	}
+	return v, src
+}
+
+// source returns where the value of f came from.
+func (o *Opts) source(f *pflag.Flag) string {
+	if f.Changed {
+		return "flag"
+	}
+	if src, ok := o.sources[f.Name]; ok {
+		return src
+	}
+	return "default"
+}
+
 func (o *Opts) Int64(envKey, flag, shortFlag string, defaultVal int64, usage string) (*int64, error) {
-	if env := o.getEnv(flag, envKey); env != "" {
+	p := new(int64)
+	err := o.int64Var(p, envKey, flag, shortFlag, defaultVal, usage)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (o *Opts) int64Var(p *int64, envKey, flag, shortFlag string, defaultVal int64, usage string) error {
+	if env, src := o.getValue(flag, envKey); env != "" {
 		envVal, err := strconv.ParseInt(env, 10, 64)
 		if err != nil {
-			return nil, UsageErrorf(`invalid environment variable %s. Expected int64. Found "%v".`, envKey, envVal)
+			return UsageErrorf(`invalid %s. Expected int64. Found "%v".`, src, env)
 		}
 		defaultVal = envVal
 	}
 
-	return o.Flags.Int64P(flag, shortFlag, defaultVal, usage), nil
+	o.Flags.Int64VarP(p, flag, shortFlag, defaultVal, usage)
+	return nil
 }
 
 func (o *Opts) Int64Slice(envKey, flag, shortFlag string, defaultVal []int64, usage string) (*[]int64, error) {
-	if env := o.getEnv(flag, envKey); env != "" {
+	p := new([]int64)
+	err := o.int64SliceVar(p, envKey, flag, shortFlag, defaultVal, usage)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (o *Opts) int64SliceVar(p *[]int64, envKey, flag, shortFlag string, defaultVal []int64, usage string) error {
+	if env, src := o.getValue(flag, envKey); env != "" {
 		split := strings.Split(env, ",")
 		defaultVal = make([]int64, len(split))
 		for i, part := range split {
 			val, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
 			if err != nil {
-				return nil, UsageErrorf(`invalid environment variable %s. Expected []int64. Found "%v".`, envKey, env)
+				return UsageErrorf(`invalid %s. Expected []int64. Found "%v".`, src, env)
 			}
 			defaultVal[i] = val
 		}
 	}
 
-	return o.Flags.Int64SliceP(flag, shortFlag, defaultVal, usage), nil
+	o.Flags.Int64SliceVarP(p, flag, shortFlag, defaultVal, usage)
+	return nil
 }
 
 func (o *Opts) Float64(envKey, flag, shortFlag string, defaultVal float64, usage string) (*float64, error) {
-	if env := o.getEnv(flag, envKey); env != "" {
+	p := new(float64)
+	err := o.float64Var(p, envKey, flag, shortFlag, defaultVal, usage)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (o *Opts) float64Var(p *float64, envKey, flag, shortFlag string, defaultVal float64, usage string) error {
+	if env, src := o.getValue(flag, envKey); env != "" {
 		envVal, err := strconv.ParseFloat(env, 64)
 		if err != nil {
-			return nil, UsageErrorf(`invalid environment variable %s. Expected float64. Found "%v".`, envKey, envVal)
+			return UsageErrorf(`invalid %s. Expected float64. Found "%v".`, src, env)
 		}
 		defaultVal = envVal
 	}
 
-	return o.Flags.Float64P(flag, shortFlag, defaultVal, usage), nil
+	o.Flags.Float64VarP(p, flag, shortFlag, defaultVal, usage)
+	return nil
 }
 
 func (o *Opts) String(envKey, flag, shortFlag string, defaultVal, usage string) *string {
-	if env := o.getEnv(flag, envKey); env != "" {
+	p := new(string)
+	o.stringVar(p, envKey, flag, shortFlag, defaultVal, usage)
+	return p
+}
+
+func (o *Opts) stringVar(p *string, envKey, flag, shortFlag string, defaultVal, usage string) {
+	if env, _ := o.getValue(flag, envKey); env != "" {
 		defaultVal = env
 	}
 
-	return o.Flags.StringP(flag, shortFlag, defaultVal, usage)
+	o.Flags.StringVarP(p, flag, shortFlag, defaultVal, usage)
 }
 
 func (o *Opts) Bool(envKey, flag, shortFlag string, defaultVal bool, usage string) (*bool, error) {
-	if env := o.getEnv(flag, envKey); env != "" {
+	p := new(bool)
+	err := o.boolVar(p, envKey, flag, shortFlag, defaultVal, usage)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (o *Opts) boolVar(p *bool, envKey, flag, shortFlag string, defaultVal bool, usage string) error {
+	if env, src := o.getValue(flag, envKey); env != "" {
 		if !boolyEnv(env) {
-			return nil, UsageErrorf(`invalid environment variable %s. Expected bool. Found "%s".`, envKey, env)
+			return UsageErrorf(`invalid %s. Expected bool. Found "%s".`, src, env)
 		}
 		if truthyEnv(env) {
 			defaultVal = true
@@ -183,7 +315,8 @@ func (o *Opts) Bool(envKey, flag, shortFlag string, defaultVal bool, usage strin
 		}
 	}
 
-	return o.Flags.BoolP(flag, shortFlag, defaultVal, usage), nil
+	o.Flags.BoolVarP(p, flag, shortFlag, defaultVal, usage)
+	return nil
 }
 
 func boolyEnv(s string) bool {
@@ -197,3 +330,306 @@ func falseyEnv(s string) bool {
 func truthyEnv(s string) bool {
 	return s == "1" || s == "true"
 }
+
+func (o *Opts) Uint(envKey, flag, shortFlag string, defaultVal uint, usage string) (*uint, error) {
+	p := new(uint)
+	err := o.uintVar(p, envKey, flag, shortFlag, defaultVal, usage)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (o *Opts) uintVar(p *uint, envKey, flag, shortFlag string, defaultVal uint, usage string) error {
+	if env, src := o.getValue(flag, envKey); env != "" {
+		envVal, err := strconv.ParseUint(env, 10, 0)
+		if err != nil {
+			return UsageErrorf(`invalid %s. Expected uint. Found "%v".`, src, env)
+		}
+		defaultVal = uint(envVal)
+	}
+
+	o.Flags.UintVarP(p, flag, shortFlag, defaultVal, usage)
+	return nil
+}
+
+func (o *Opts) Duration(envKey, flag, shortFlag string, defaultVal time.Duration, usage string) (*time.Duration, error) {
+	p := new(time.Duration)
+	err := o.durationVar(p, envKey, flag, shortFlag, defaultVal, usage)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (o *Opts) durationVar(p *time.Duration, envKey, flag, shortFlag string, defaultVal time.Duration, usage string) error {
+	if env, src := o.getValue(flag, envKey); env != "" {
+		envVal, err := time.ParseDuration(env)
+		if err != nil {
+			return UsageErrorf(`invalid %s. Expected duration like 1m30s. Found "%v".`, src, env)
+		}
+		defaultVal = envVal
+	}
+
+	o.Flags.DurationVarP(p, flag, shortFlag, defaultVal, usage)
+	return nil
+}
+
+func (o *Opts) StringSlice(envKey, flag, shortFlag string, defaultVal []string, usage string) *[]string {
+	p := new([]string)
+	o.stringSliceVar(p, envKey, flag, shortFlag, defaultVal, usage)
+	return p
+}
+
+func (o *Opts) stringSliceVar(p *[]string, envKey, flag, shortFlag string, defaultVal []string, usage string) {
+	if env, _ := o.getValue(flag, envKey); env != "" {
+		split := strings.Split(env, ",")
+		defaultVal = make([]string, len(split))
+		for i, part := range split {
+			defaultVal[i] = strings.TrimSpace(part)
+		}
+	}
+
+	o.Flags.StringSliceVarP(p, flag, shortFlag, defaultVal, usage)
+}
+
+func (o *Opts) StringToString(envKey, flag, shortFlag string, defaultVal map[string]string, usage string) (*map[string]string, error) {
+	p := new(map[string]string)
+	err := o.stringToStringVar(p, envKey, flag, shortFlag, defaultVal, usage)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (o *Opts) stringToStringVar(p *map[string]string, envKey, flag, shortFlag string, defaultVal map[string]string, usage string) error {
+	if env, src := o.getValue(flag, envKey); env != "" {
+		defaultVal = make(map[string]string)
+		for _, part := range strings.Split(env, ",") {
+			k, v, ok := strings.Cut(part, "=")
+			if !ok {
+				return UsageErrorf(`invalid %s. Expected key=value pairs separated by commas. Found "%v".`, src, env)
+			}
+			defaultVal[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+
+	o.Flags.StringToStringVarP(p, flag, shortFlag, defaultVal, usage)
+	return nil
+}
+
+// Enum declares a string option that must be one of allowed.
+// The allowed values are listed in its usage and completed by the shell.
+// defaultVal must be one of allowed or empty for no default.
+func (o *Opts) Enum(envKey, flag, shortFlag string, defaultVal string, allowed []string, usage string) (*string, error) {
+	p := new(string)
+	err := o.enumVar(p, envKey, flag, shortFlag, defaultVal, allowed, usage)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (o *Opts) enumVar(p *string, envKey, flag, shortFlag string, defaultVal string, allowed []string, usage string) error {
+	if defaultVal != "" && !go2.Contains(allowed, defaultVal) {
+		return fmt.Errorf(`invalid default %q for --%s: expected one of %s`, defaultVal, flag, strings.Join(allowed, ", "))
+	}
+	if env, src := o.getValue(flag, envKey); env != "" {
+		if !go2.Contains(allowed, env) {
+			return UsageErrorf(`invalid %s. Expected one of %s. Found "%v".`, src, strings.Join(allowed, ", "), env)
+		}
+		defaultVal = env
+	}
+
+	*p = defaultVal
+	v := &enumValue{
+		p:       p,
+		allowed: allowed,
+	}
+	o.Flags.VarP(v, flag, shortFlag, fmt.Sprintf("%s (one of %s)", usage, strings.Join(allowed, ", ")))
+	o.Complete(flag, CompleteValues(allowed...))
+	return nil
+}
+
+type enumValue struct {
+	p       *string
+	allowed []string
+}
+
+func (ev *enumValue) String() string {
+	return *ev.p
+}
+
+func (ev *enumValue) Set(s string) error {
+	if !go2.Contains(ev.allowed, s) {
+		return fmt.Errorf("expected one of %s", strings.Join(ev.allowed, ", "))
+	}
+	*ev.p = s
+	return nil
+}
+
+// Type is string so that Defaults quotes the default like a string.
+func (ev *enumValue) Type() string {
+	return "string"
+}
+
+// ByteSize declares a size in bytes that accepts units like 10MB or 1.5GiB.
+// KB, MB, GB and TB are powers of 1000 while KiB, MiB, GiB and TiB are powers of
+// 1024. Units are case insensitive and the B may be omitted.
+func (o *Opts) ByteSize(envKey, flag, shortFlag string, defaultVal int64, usage string) (*int64, error) {
+	p := new(int64)
+	err := o.byteSizeVar(p, envKey, flag, shortFlag, defaultVal, usage)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (o *Opts) byteSizeVar(p *int64, envKey, flag, shortFlag string, defaultVal int64, usage string) error {
+	if env, src := o.getValue(flag, envKey); env != "" {
+		envVal, err := parseByteSize(env)
+		if err != nil {
+			return UsageErrorf(`invalid %s. Expected size like 10MB. Found "%v".`, src, env)
+		}
+		defaultVal = envVal
+	}
+
+	*p = defaultVal
+	o.Flags.VarP((*byteSizeValue)(p), flag, shortFlag, usage)
+	return nil
+}
+
+type byteSizeValue int64
+
+func (bv *byteSizeValue) String() string {
+	return formatByteSize(int64(*bv))
+}
+
+func (bv *byteSizeValue) Set(s string) error {
+	n, err := parseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*bv = byteSizeValue(n)
+	return nil
+}
+
+func (bv *byteSizeValue) Type() string {
+	return "size"
+}
+
+var byteUnits = []struct {
+	suffix string
+	n      int64
+}{
+	{"kib", 1 << 10},
+	{"mib", 1 << 20},
+	{"gib", 1 << 30},
+	{"tib", 1 << 40},
+	{"kb", 1e3},
+	{"mb", 1e6},
+	{"gb", 1e9},
+	{"tb", 1e12},
+	{"ki", 1 << 10},
+	{"mi", 1 << 20},
+	{"gi", 1 << 30},
+	{"ti", 1 << 40},
+	{"k", 1e3},
+	{"m", 1e6},
+	{"g", 1e9},
+	{"t", 1e12},
+	{"b", 1},
+}
+
+func parseByteSize(s string) (int64, error) {
+	s2 := strings.ToLower(strings.TrimSpace(s))
+	mult := int64(1)
+	for _, u := range byteUnits {
+		if strings.HasSuffix(s2, u.suffix) {
+			s2 = strings.TrimSpace(strings.TrimSuffix(s2, u.suffix))
+			mult = u.n
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(s2, 64)
+	if err != nil || f < 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	n := f * float64(mult)
+	// float64(math.MaxInt64) rounds up to 1<<63 so >= catches every overflow.
+	if n >= math.MaxInt64 {
+		return 0, fmt.Errorf("size %q is too large", s)
+	}
+	return int64(n), nil
+}
+
+// formatByteSize formats n with the largest unit that divides it exactly.
+func formatByteSize(n int64) string {
+	for _, unit := range []struct {
+		suffix string
+		n      int64
+	}{
+		{"TiB", 1 << 40}, {"TB", 1e12},
+		{"GiB", 1 << 30}, {"GB", 1e9},
+		{"MiB", 1 << 20}, {"MB", 1e6},
+		{"KiB", 1 << 10}, {"KB", 1e3},
+	} {
+		if n != 0 && n%unit.n == 0 {
+			return fmt.Sprintf("%d%s", n/unit.n, unit.suffix)
+		}
+	}
+	return fmt.Sprintf("%dB", n)
+}
+
+// Path declares a file path option. Relative paths are resolved against the PWD
+// like State.AbsPath. - is left as is to mean stdin or stdout.
+// Paths are completed by the shell.
+func (o *Opts) Path(envKey, flag, shortFlag string, defaultVal, usage string) *string {
+	p := new(string)
+	o.pathVar(p, envKey, flag, shortFlag, defaultVal, usage)
+	return p
+}
+
+func (o *Opts) pathVar(p *string, envKey, flag, shortFlag string, defaultVal, usage string) {
+	if env, _ := o.getValue(flag, envKey); env != "" {
+		defaultVal = env
+	}
+
+	v := &pathValue{o: o, p: p}
+	_ = v.Set(defaultVal)
+	o.Flags.VarP(v, flag, shortFlag, usage)
+	// Show the default as given rather than resolved.
+	o.Flags.Lookup(flag).DefValue = defaultVal
+	o.Complete(flag, CompleteFiles())
+}
+
+type pathValue struct {
+	o *Opts
+	p *string
+}
+
+func (pv *pathValue) String() string {
+	return *pv.p
+}
+
+func (pv *pathValue) Set(s string) error {
+	if s == "" || s == "-" {
+		*pv.p = s
+		return nil
+	}
+	if pv.o.pwd == "" {
+		fp, err := filepath.Abs(s)
+		if err != nil {
+			return err
+		}
+		*pv.p = fp
+		return nil
+	}
+	*pv.p = absPath(pv.o.pwd, s)
+	return nil
+}
+
+// Type is string so that Defaults quotes the default like a string.
+func (pv *pathValue) Type() string {
+	return "string"
+}
@@ -0,0 +1,177 @@
+package xmain_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"oss.terrastruct.com/util-go/assert"
+	"oss.terrastruct.com/util-go/xmain"
+	"oss.terrastruct.com/util-go/xos"
+)
+
+func TestOptsTypes(t *testing.T) {
+	t.Parallel()
+
+	env := xos.NewEnv([]string{
+		"TIMEOUT=1m30s",
+		"TAGS=a, b",
+		"LABELS= team = d2",
+		"FORMAT=yaml",
+		"WORKERS=4",
+		"MAX_SIZE=1.5KiB",
+		"OUT=out.svg",
+	})
+	o := xmain.NewOpts(env, []string{"--labels=env=prod", "--tags=c", "--max-size", "10MB", "--input", "/in.d2"})
+
+	timeout, err := o.Duration("TIMEOUT", "timeout", "", time.Second, "timeout")
+	assert.Success(t, err)
+	tags := o.StringSlice("TAGS", "tags", "", nil, "tags")
+	labels, err := o.StringToString("LABELS", "labels", "", nil, "labels")
+	assert.Success(t, err)
+	format, err := o.Enum("FORMAT", "format", "f", "json", []string{"json", "yaml"}, "output format")
+	assert.Success(t, err)
+	workers, err := o.Uint("WORKERS", "workers", "", 1, "workers")
+	assert.Success(t, err)
+	maxSize, err := o.ByteSize("MAX_SIZE", "max-size", "", 1<<20, "max size")
+	assert.Success(t, err)
+	out := o.Path("OUT", "out", "", "", "output path")
+	input := o.Path("", "input", "", "-", "input path")
+
+	err = o.Flags.Parse(o.Args)
+	assert.Success(t, err)
+
+	assert.Equal(t, time.Minute+time.Second*30, *timeout)
+	assert.StringJSON(t, `[
+  "c"
+]`, *tags)
+	assert.StringJSON(t, `{
+  "env": "prod"
+}`, *labels)
+	assert.Equal(t, "yaml", *format)
+	assert.Equal(t, uint(4), *workers)
+	assert.Equal(t, int64(10e6), *maxSize)
+	if !filepath.IsAbs(*out) || filepath.Base(*out) != "out.svg" {
+		t.Fatalf("expected absolute path: %q", *out)
+	}
+	assert.Equal(t, "/in.d2", *input)
+
+	assert.String(t, `      --timeout duration        $TIMEOUT   timeout (default 1m30s)
+      --tags strings            $TAGS      tags (default [a,b])
+      --labels stringToString   $LABELS    labels (default [team=d2])
+  -f, --format string           $FORMAT    output format (one of json, yaml) (default "yaml")
+      --workers uint            $WORKERS   workers (default 4)
+      --max-size size           $MAX_SIZE  max size (default 1536B)
+      --out string              $OUT       output path (default "out.svg")
+      --input string                       input path (default "-")
+`, o.Defaults())
+
+	err = o.Flags.Parse([]string{"--format", "toml"})
+	assert.ErrorString(t, err, `invalid argument "toml" for "-f, --format" flag: expected one of json, yaml`)
+
+	tca := []struct {
+		name    string
+		environ []string
+		declare func(o *xmain.Opts) error
+		exp     string
+	}{
+		{
+			name:    "Duration",
+			environ: []string{"TIMEOUT=5"},
+			declare: func(o *xmain.Opts) error {
+				_, err := o.Duration("TIMEOUT", "timeout", "", 0, "")
+				return err
+			},
+			exp: `bad usage: invalid environment variable TIMEOUT. Expected duration like 1m30s. Found "5".`,
+		},
+		{
+			name:    "StringToString",
+			environ: []string{"LABELS=prod"},
+			declare: func(o *xmain.Opts) error {
+				_, err := o.StringToString("LABELS", "labels", "", nil, "")
+				return err
+			},
+			exp: `bad usage: invalid environment variable LABELS. Expected key=value pairs separated by commas. Found "prod".`,
+		},
+		{
+			name:    "Enum",
+			environ: []string{"FORMAT=toml"},
+			declare: func(o *xmain.Opts) error {
+				_, err := o.Enum("FORMAT", "format", "", "json", []string{"json", "yaml"}, "")
+				return err
+			},
+			exp: `bad usage: invalid environment variable FORMAT. Expected one of json, yaml. Found "toml".`,
+		},
+		{
+			name: "EnumDefault",
+			declare: func(o *xmain.Opts) error {
+				_, err := o.Enum("FORMAT", "format", "", "xml", []string{"json", "yaml"}, "")
+				return err
+			},
+			exp: `invalid default "xml" for --format: expected one of json, yaml`,
+		},
+		{
+			name:    "Uint",
+			environ: []string{"WORKERS=-1"},
+			declare: func(o *xmain.Opts) error {
+				_, err := o.Uint("WORKERS", "workers", "", 0, "")
+				return err
+			},
+			exp: `bad usage: invalid environment variable WORKERS. Expected uint. Found "-1".`,
+		},
+		{
+			name:    "ByteSize",
+			environ: []string{"MAX_SIZE=10XB"},
+			declare: func(o *xmain.Opts) error {
+				_, err := o.ByteSize("MAX_SIZE", "max-size", "", 0, "")
+				return err
+			},
+			exp: `bad usage: invalid environment variable MAX_SIZE. Expected size like 10MB. Found "10XB".`,
+		},
+		{
+			name:    "ByteSizeNaN",
+			environ: []string{"MAX_SIZE=NaN"},
+			declare: func(o *xmain.Opts) error {
+				_, err := o.ByteSize("MAX_SIZE", "max-size", "", 0, "")
+				return err
+			},
+			exp: `bad usage: invalid environment variable MAX_SIZE. Expected size like 10MB. Found "NaN".`,
+		},
+		{
+			name:    "ByteSizeInf",
+			environ: []string{"MAX_SIZE=+Inf"},
+			declare: func(o *xmain.Opts) error {
+				_, err := o.ByteSize("MAX_SIZE", "max-size", "", 0, "")
+				return err
+			},
+			exp: `bad usage: invalid environment variable MAX_SIZE. Expected size like 10MB. Found "+Inf".`,
+		},
+		{
+			name:    "ByteSizeOverflow",
+			environ: []string{"MAX_SIZE=9223372036854775808"},
+			declare: func(o *xmain.Opts) error {
+				_, err := o.ByteSize("MAX_SIZE", "max-size", "", 0, "")
+				return err
+			},
+			exp: `bad usage: invalid environment variable MAX_SIZE. Expected size like 10MB. Found "9223372036854775808".`,
+		},
+		{
+			name:    "ByteSizeOverflowUnit",
+			environ: []string{"MAX_SIZE=9000000TiB"},
+			declare: func(o *xmain.Opts) error {
+				_, err := o.ByteSize("MAX_SIZE", "max-size", "", 0, "")
+				return err
+			},
+			exp: `bad usage: invalid environment variable MAX_SIZE. Expected size like 10MB. Found "9000000TiB".`,
+		},
+	}
+	for _, tc := range tca {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.declare(xmain.NewOpts(xos.NewEnv(tc.environ), nil))
+			assert.ErrorString(t, err, tc.exp)
+		})
+	}
+}
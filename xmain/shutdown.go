@@ -0,0 +1,177 @@
+package xmain
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"oss.terrastruct.com/util-go/xcontext"
+)
+
+// DefaultShutdownTimeout is how long Main waits for the program to shutdown unless
+// changed with State.SetShutdownTimeout.
+const DefaultShutdownTimeout = time.Minute
+
+type shutdownHook struct {
+	// owner is the State the hook was registered on.
+	owner   *State
+	name    string
+	timeout time.Duration
+	fn      func(ctx context.Context) error
+}
+
+// SetShutdownTimeout sets how long Main waits for the RunFunc to return and the
+// shutdown hooks to run once shutting down. Main exits with code 1 once it passes.
+// It may be called from the RunFunc at any time.
+func (ms *State) SetShutdownTimeout(d time.Duration) {
+	ms = ms.root()
+	ms.shutdownMu.Lock()
+	defer ms.shutdownMu.Unlock()
+	ms.shutdownTimeout = d
+}
+
+// OnShutdown registers fn to be run when Main shuts down. That is on the first
+// SIGINT or SIGTERM or once the RunFunc returns.
+//
+// Hooks run one at a time in the reverse order of their registration so that
+// resources are released in the reverse order of their acquisition. The context
+// passed to fn is done after timeout or once the shutdown timeout set with
+// SetShutdownTimeout is exceeded. A timeout of 0 leaves only the shutdown timeout.
+// A hook that returns an error or does not return in time is logged and Main exits
+// with code 1.
+//
+// A second SIGINT while shutting down exits immediately. The hooks still pending are
+// logged when exiting early.
+//
+// On a signal, the hooks run while the context passed to the RunFunc is canceled so
+// that servers may drain their connections:
+//
+//	ms.OnShutdown("http", 30*time.Second, srv.Shutdown)
+//	err := srv.Serve(l)
+//	if errors.Is(err, http.ErrServerClosed) {
+//		return nil
+//	}
+//	return err
+func (ms *State) OnShutdown(name string, timeout time.Duration, fn func(ctx context.Context) error) {
+	owner := ms
+	ms = ms.root()
+	ms.shutdownMu.Lock()
+	defer ms.shutdownMu.Unlock()
+	ms.hooks = append(ms.hooks, shutdownHook{
+		owner:   owner,
+		name:    name,
+		timeout: timeout,
+		fn:      fn,
+	})
+}
+
+// shutdown runs the shutdown hooks and, if done is not nil, waits for the RunFunc to
+// return its error on done.
+//
+// ok is false if a hook failed. err is an ExitError if the shutdown was cut short by
+// an interrupt or by exceeding the shutdown timeout.
+func (ms *State) shutdown(ctx context.Context, sigs <-chan os.Signal, done <-chan error) (runErr error, ok bool, err error) {
+	ms.shutdownMu.Lock()
+	timeout := ms.shutdownTimeout
+	if timeout == 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	hooks := make([]shutdownHook, len(ms.hooks))
+	for i, h := range ms.hooks {
+		hooks[len(hooks)-1-i] = h
+	}
+	ms.shutdownMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(xcontext.WithoutCancel(ctx), timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	pending := hooks
+	hooksDone := make(chan bool, 1)
+	go func() {
+		// Wait for any running signal hook so that the program is not reloaded
+		// while being shutdown.
+		if ms.signalDone != nil {
+			<-ms.signalDone
+		}
+		ok := true
+		for _, h := range hooks {
+			err := h.run(ctx)
+			if err != nil {
+				ms.Log.Error.Printf("failed to run shutdown hook %s: %v", h.name, err)
+				ok = false
+			}
+			mu.Lock()
+			pending = pending[1:]
+			mu.Unlock()
+		}
+		hooksDone <- ok
+	}()
+
+	logPending := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(pending) == 0 {
+			return
+		}
+		names := make([]string, len(pending))
+		for i, h := range pending {
+			names[i] = h.name
+		}
+		ms.Log.Warn.Printf("shutdown hooks still pending: %s", strings.Join(names, ", "))
+	}
+
+	for hooksDone != nil || done != nil {
+		select {
+		case ok = <-hooksDone:
+			hooksDone = nil
+		case runErr = <-done:
+			done = nil
+		case sig := <-sigs:
+			if sig != os.Interrupt {
+				ms.Log.Warn.Printf("received signal %v: already shutting down", sig)
+				continue
+			}
+			logPending()
+			return nil, false, ExitError{
+				Code:    1,
+				Message: "received second interrupt: exiting forcefully",
+			}
+		case <-ctx.Done():
+			logPending()
+			return nil, false, ExitError{
+				Code:    1,
+				Message: fmt.Sprintf("took longer than %v to shutdown: exiting forcefully", timeout),
+			}
+		}
+	}
+	return runErr, ok, nil
+}
+
+// run runs the hook and gives up on it once its timeout is exceeded. Exceeding the
+// shutdown timeout is left to shutdown so that the hook is logged as pending.
+func (h shutdownHook) run(ctx context.Context) error {
+	var timeout <-chan time.Time
+	if h.timeout > 0 {
+		var cancel func()
+		ctx, cancel = context.WithTimeout(ctx, h.timeout)
+		defer cancel()
+		t := time.NewTimer(h.timeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- h.fn(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-timeout:
+		return fmt.Errorf("did not return within %v", h.timeout)
+	}
+}
@@ -0,0 +1,176 @@
+package xmain_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"oss.terrastruct.com/util-go/assert"
+	"oss.terrastruct.com/util-go/xmain"
+)
+
+func TestShutdown(t *testing.T) {
+	t.Parallel()
+
+	t.Run("order", func(t *testing.T) {
+		t.Parallel()
+
+		var mu sync.Mutex
+		var order []string
+		hook := func(name string) func(context.Context) error {
+			return func(context.Context) error {
+				mu.Lock()
+				defer mu.Unlock()
+				order = append(order, name)
+				return nil
+			}
+		}
+
+		ctx := context.Background()
+		ts := &xmain.TestState{
+			Run: func(ctx context.Context, ms *xmain.State) error {
+				ms.OnShutdown("db", 0, hook("db"))
+				ms.OnShutdown("http", time.Second, hook("http"))
+				return nil
+			},
+			Args: []string{"shutdown"},
+		}
+		ts.Start(t, ctx)
+		defer ts.Cleanup(t)
+
+		err := ts.Wait(ctx)
+		assert.Success(t, err)
+		assert.StringJSON(t, `[
+  "http",
+  "db"
+]`, order)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		t.Parallel()
+
+		stderr := &strings.Builder{}
+		ctx := context.Background()
+		ts := &xmain.TestState{
+			Run: func(ctx context.Context, ms *xmain.State) error {
+				ms.OnShutdown("cache", time.Millisecond*10, func(context.Context) error {
+					select {}
+				})
+				ms.OnShutdown("db", 0, func(context.Context) error {
+					return errors.New("connection reset")
+				})
+				return nil
+			},
+			Args:   []string{"shutdown"},
+			Stderr: stderr,
+		}
+		ts.Start(t, ctx)
+		defer ts.Cleanup(t)
+
+		err := ts.Wait(ctx)
+		assert.ErrorString(t, err, `failed to wait xmain test: shutdown: exiting with code 1`)
+		assertContains(t, stderr.String(), `err: failed to run shutdown hook db: connection reset
+err: failed to run shutdown hook cache: did not return within 10ms
+`)
+	})
+
+	t.Run("signal", func(t *testing.T) {
+		t.Parallel()
+
+		drained := make(chan struct{})
+		ctx := context.Background()
+		ts, ready := startShutdownTest(t, ctx, nil, func(ms *xmain.State) {
+			ms.OnShutdown("http", time.Second, func(context.Context) error {
+				close(drained)
+				return nil
+			})
+		})
+		defer ts.Cleanup(t)
+		<-ready
+
+		err := ts.Signal(ctx, syscall.SIGTERM)
+		assert.Success(t, err)
+		err = ts.Wait(ctx)
+		assert.Success(t, err)
+		<-drained
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+
+		stderr := &strings.Builder{}
+		ctx := context.Background()
+		ts, ready := startShutdownTest(t, ctx, stderr, func(ms *xmain.State) {
+			ms.SetShutdownTimeout(time.Millisecond * 50)
+			ms.OnShutdown("stuck", 0, func(context.Context) error {
+				select {}
+			})
+		})
+		defer ts.Cleanup(t)
+		<-ready
+
+		err := ts.Signal(ctx, syscall.SIGTERM)
+		assert.Success(t, err)
+		err = ts.Wait(ctx)
+		assert.ErrorString(t, err, `failed to wait xmain test: shutdown: exiting with code 1: took longer than 50ms to shutdown: exiting forcefully`)
+		assertContains(t, stderr.String(), "shutdown hooks still pending: stuck")
+	})
+
+	t.Run("second_interrupt", func(t *testing.T) {
+		t.Parallel()
+
+		stderr := &strings.Builder{}
+		ctx := context.Background()
+		ts, ready := startShutdownTest(t, ctx, stderr, func(ms *xmain.State) {
+			ms.OnShutdown("db", 0, func(context.Context) error {
+				return nil
+			})
+			ms.OnShutdown("stuck", time.Minute, func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			})
+		})
+		defer ts.Cleanup(t)
+		<-ready
+
+		err := ts.Signal(ctx, os.Interrupt)
+		assert.Success(t, err)
+		err = ts.Signal(ctx, os.Interrupt)
+		assert.Success(t, err)
+		err = ts.Wait(ctx)
+		assert.ErrorString(t, err, `failed to wait xmain test: shutdown: exiting with code 1: received second interrupt: exiting forcefully`)
+		assertContains(t, stderr.String(), "shutdown hooks still pending: stuck, db")
+	})
+}
+
+// startShutdownTest starts a program that calls register and then waits to be
+// signaled. ready is closed once register returns.
+func startShutdownTest(t *testing.T, ctx context.Context, stderr *strings.Builder, register func(ms *xmain.State)) (_ *xmain.TestState, ready chan struct{}) {
+	ready = make(chan struct{})
+	ts := &xmain.TestState{
+		Run: func(ctx context.Context, ms *xmain.State) error {
+			register(ms)
+			close(ready)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		Args: []string{"shutdown"},
+	}
+	if stderr != nil {
+		ts.Stderr = stderr
+	}
+	ts.Start(t, ctx)
+	return ts, ready
+}
+
+func assertContains(t *testing.T, s, substr string) {
+	t.Helper()
+	if !strings.Contains(s, substr) {
+		t.Fatalf("expected %q to contain %q", s, substr)
+	}
+}
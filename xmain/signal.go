@@ -0,0 +1,130 @@
+package xmain
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+type signalHook struct {
+	// owner is the State the hook was registered on.
+	owner *State
+	name  string
+	fn    func(ctx context.Context) error
+}
+
+// OnReload registers fn to be run on SIGHUP to reload configuration, reopen log files
+// and the like without restarting. It is short for OnSignal(syscall.SIGHUP, name, fn).
+func (ms *State) OnReload(name string, fn func(ctx context.Context) error) {
+	ms.OnSignal(syscall.SIGHUP, name, fn)
+}
+
+// OnSignal registers fn to be run when Main receives sig. sig must be SIGHUP, SIGUSR1
+// or SIGUSR2. OnSignal panics for any other signal as the shutdown signals SIGINT
+// and SIGTERM are handled by Main and hooks for other signals would never run.
+//
+// Main subscribes to sig once the first hook for it is registered. SIGHUP is not
+// subscribed to if it is ignored, as under nohup, so that the program keeps running
+// once the terminal closes.
+//
+// Hooks are run in the order they were registered with the context passed to the
+// RunFunc. Hooks of all signals are run one at a time by a single goroutine. A signal
+// received while its hooks are already waiting to run is collapsed into the pending
+// run. An error from a hook is logged to ms.Log and does not stop the program or the
+// hooks after it.
+//
+// On shutdown, hooks that have not started are dropped and the shutdown hooks run
+// once the running hook returns.
+func (ms *State) OnSignal(sig os.Signal, name string, fn func(ctx context.Context) error) {
+	if !hasSignal(hookSignals, sig) {
+		panic(fmt.Sprintf("xmain: cannot register signal hook %s for %v: expected one of %v", name, sig, hookSignals))
+	}
+
+	owner := ms
+	ms = ms.root()
+	ms.signalMu.Lock()
+	defer ms.signalMu.Unlock()
+	if ms.signalHooks == nil {
+		ms.signalHooks = make(map[os.Signal][]signalHook)
+	}
+	if len(ms.signalHooks[sig]) == 0 && ms.notify != nil && !signal.Ignored(sig) {
+		ms.notify(sig)
+	}
+	ms.signalHooks[sig] = append(ms.signalHooks[sig], signalHook{
+		owner: owner,
+		name:  name,
+		fn:    fn,
+	})
+}
+
+func hasSignal(sigs []os.Signal, sig os.Signal) bool {
+	for _, s := range sigs {
+		if s == sig {
+			return true
+		}
+	}
+	return false
+}
+
+// handleSignal queues the hooks registered for sig to be run by runSignalHooks and
+// reports whether sig was handled. Signals OnSignal accepts are always handled. A
+// signal that is already queued is not queued again so that repeated signals
+// collapse into one run.
+func (ms *State) handleSignal(sig os.Signal) bool {
+	ms.signalMu.Lock()
+	n := len(ms.signalHooks[sig])
+	queued := hasSignal(ms.signalPending, sig)
+	if n > 0 && !queued {
+		ms.signalPending = append(ms.signalPending, sig)
+	}
+	ms.signalMu.Unlock()
+	if n == 0 {
+		if !hasSignal(hookSignals, sig) {
+			return false
+		}
+		// Main subscribed to sig for hooks that have since been dropped with the
+		// run of a Supervisor component that registered them.
+		ms.Log.Warn.Printf("received signal %v: no hooks registered", sig)
+		return true
+	}
+
+	if queued {
+		ms.Log.Info.Printf("received signal %v: hook(s) already pending", sig)
+		return true
+	}
+	ms.Log.Info.Printf("received signal %v: running %d hook(s)", sig, n)
+	select {
+	case ms.signalWake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// runSignalHooks runs the hooks of the signals queued by handleSignal one signal at
+// a time until ms.signalWake is closed. Signals still queued once ctx is canceled are
+// dropped as the program is shutting down.
+func (ms *State) runSignalHooks(ctx context.Context) {
+	defer close(ms.signalDone)
+	for range ms.signalWake {
+		for ctx.Err() == nil {
+			ms.signalMu.Lock()
+			if len(ms.signalPending) == 0 {
+				ms.signalMu.Unlock()
+				break
+			}
+			sig := ms.signalPending[0]
+			ms.signalPending = ms.signalPending[1:]
+			hooks := ms.signalHooks[sig]
+			ms.signalMu.Unlock()
+
+			for _, h := range hooks {
+				err := h.fn(ctx)
+				if err != nil {
+					ms.Log.Error.Printf("failed to run %v hook %s: %v", sig, h.name, err)
+				}
+			}
+		}
+	}
+}
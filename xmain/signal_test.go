@@ -0,0 +1,181 @@
+//go:build !windows
+
+package xmain_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"oss.terrastruct.com/util-go/assert"
+	"oss.terrastruct.com/util-go/xmain"
+)
+
+func TestOnSignal(t *testing.T) {
+	t.Parallel()
+
+	t.Run("collapsed", func(t *testing.T) {
+		t.Parallel()
+
+		var running, overlaps, reloads int32
+		started := make(chan struct{}, 2)
+		release := make(chan struct{})
+		stats := make(chan struct{})
+		var statsOnce sync.Once
+		ctx := context.Background()
+		ts, ready := startShutdownTest(t, ctx, nil, func(ms *xmain.State) {
+			ms.OnReload("config", func(context.Context) error {
+				if atomic.AddInt32(&running, 1) > 1 {
+					atomic.AddInt32(&overlaps, 1)
+				}
+				defer atomic.AddInt32(&running, -1)
+				atomic.AddInt32(&reloads, 1)
+				started <- struct{}{}
+				<-release
+				return nil
+			})
+			ms.OnSignal(syscall.SIGUSR1, "stats", func(context.Context) error {
+				if atomic.AddInt32(&running, 1) > 1 {
+					atomic.AddInt32(&overlaps, 1)
+				}
+				defer atomic.AddInt32(&running, -1)
+				statsOnce.Do(func() { close(stats) })
+				return nil
+			})
+		})
+		defer ts.Cleanup(t)
+		<-ready
+
+		err := ts.Signal(ctx, syscall.SIGHUP)
+		assert.Success(t, err)
+		<-started
+		// Received while config is running so they collapse into one pending run.
+		// SIGUSR1 is sent twice as TestState.Signal returns before Main handles the
+		// signal and so the second ensures all SIGHUP were handled before release.
+		for _, sig := range []syscall.Signal{syscall.SIGHUP, syscall.SIGHUP, syscall.SIGHUP, syscall.SIGUSR1, syscall.SIGUSR1} {
+			err := ts.Signal(ctx, sig)
+			assert.Success(t, err)
+		}
+		close(release)
+		<-stats
+
+		err = ts.Signal(ctx, syscall.SIGTERM)
+		assert.Success(t, err)
+		err = ts.Wait(ctx)
+		assert.Success(t, err)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&reloads))
+		assert.Equal(t, int32(0), atomic.LoadInt32(&overlaps))
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		t.Parallel()
+
+		var reloads, reloading, overlapped int32
+		started := make(chan struct{})
+		ctx := context.Background()
+		ts, ready := startShutdownTest(t, ctx, nil, func(ms *xmain.State) {
+			ms.OnReload("config", func(ctx context.Context) error {
+				atomic.StoreInt32(&reloading, 1)
+				defer atomic.StoreInt32(&reloading, 0)
+				if atomic.AddInt32(&reloads, 1) == 1 {
+					close(started)
+				}
+				<-ctx.Done()
+				time.Sleep(time.Millisecond * 10)
+				return ctx.Err()
+			})
+			ms.OnShutdown("db", 0, func(context.Context) error {
+				atomic.StoreInt32(&overlapped, atomic.LoadInt32(&reloading))
+				return nil
+			})
+		})
+		defer ts.Cleanup(t)
+		<-ready
+
+		err := ts.Signal(ctx, syscall.SIGHUP)
+		assert.Success(t, err)
+		<-started
+		// Dropped on shutdown as it has not started.
+		err = ts.Signal(ctx, syscall.SIGHUP)
+		assert.Success(t, err)
+
+		err = ts.Signal(ctx, syscall.SIGTERM)
+		assert.Success(t, err)
+		err = ts.Wait(ctx)
+		assert.Success(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&reloads))
+		assert.Equal(t, int32(0), atomic.LoadInt32(&overlapped))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+
+		stderr := &strings.Builder{}
+		reloaded := make(chan struct{})
+		ctx := context.Background()
+		ts, ready := startShutdownTest(t, ctx, stderr, func(ms *xmain.State) {
+			ms.OnReload("config", func(context.Context) error {
+				return errors.New("invalid config")
+			})
+			ms.OnReload("done", func(context.Context) error {
+				close(reloaded)
+				return nil
+			})
+		})
+		defer ts.Cleanup(t)
+		<-ready
+
+		err := ts.Signal(ctx, syscall.SIGHUP)
+		assert.Success(t, err)
+		<-reloaded
+
+		err = ts.Signal(ctx, syscall.SIGTERM)
+		assert.Success(t, err)
+		err = ts.Wait(ctx)
+		assert.Success(t, err)
+		assertContains(t, stderr.String(), "err: failed to run hangup hook config: invalid config\n")
+	})
+
+	t.Run("unhandled", func(t *testing.T) {
+		t.Parallel()
+
+		stderr := &strings.Builder{}
+		ctx := context.Background()
+		ts, ready := startShutdownTest(t, ctx, stderr, func(ms *xmain.State) {})
+		defer ts.Cleanup(t)
+		<-ready
+
+		err := ts.Signal(ctx, syscall.SIGUSR2)
+		assert.Success(t, err)
+		err = ts.Signal(ctx, syscall.SIGTERM)
+		assert.Success(t, err)
+		err = ts.Wait(ctx)
+		assert.Success(t, err)
+		assertContains(t, stderr.String(), "warn: received signal user defined signal 2: no hooks registered\n")
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		t.Parallel()
+
+		for _, sig := range []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGWINCH} {
+			func() {
+				defer func() {
+					r := recover()
+					if r == nil {
+						t.Fatalf("expected OnSignal to panic for %v", sig)
+					}
+					assertContains(t, fmt.Sprint(r), "xmain: cannot register signal hook x for "+sig.String())
+				}()
+				ms := &xmain.State{}
+				ms.OnSignal(sig, "x", func(context.Context) error { return nil })
+			}()
+		}
+	})
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package xmain
+
+import (
+	"os"
+	"syscall"
+)
+
+// hookSignals are the signals OnSignal accepts.
+var hookSignals = []os.Signal{syscall.SIGHUP, syscall.SIGUSR1, syscall.SIGUSR2}
@@ -0,0 +1,12 @@
+//go:build windows
+
+package xmain
+
+import (
+	"os"
+	"syscall"
+)
+
+// hookSignals are the signals OnSignal accepts. Windows never delivers SIGHUP but it
+// is accepted so that OnReload may be called unconditionally.
+var hookSignals = []os.Signal{syscall.SIGHUP}
@@ -0,0 +1,233 @@
+package xmain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/multierr"
+)
+
+// RestartPolicy is when a Supervisor restarts a component that returned.
+type RestartPolicy int
+
+const (
+	// RestartNever leaves a component that returned nil stopped and stops all
+	// components if it returned an error.
+	RestartNever RestartPolicy = iota
+	// RestartOnFailure restarts a component that returned an error.
+	RestartOnFailure
+	// RestartAlways restarts a component whenever it returns.
+	RestartAlways
+)
+
+// Component is a long running part of a program run by a Supervisor such as an HTTP
+// server or a background worker.
+type Component struct {
+	// Name identifies the component in logs and errors.
+	Name string
+	// Flags declares the flags of the component on o. Supervisor.Main calls the
+	// Flags of all components and then parses the arguments once before any
+	// component runs. Run reads the parsed values and must not parse ms.Opts again.
+	Flags func(o *Opts) error
+	// Run runs the component until ctx is canceled.
+	//
+	// Hooks registered with OnShutdown and OnSignal on the State passed to Run
+	// belong to that run. They are dropped when the component is restarted so that
+	// only the hooks of its latest run are left.
+	Run RunFunc
+
+	Restart RestartPolicy
+	// MaxRestarts is how many times the component is restarted in a row before its
+	// error stops all components. 0 means no limit.
+	MaxRestarts int
+	// Backoff is the delay before the first restart. It doubles with each restart in
+	// a row up to MaxBackoff. Defaults to 1 second.
+	Backoff time.Duration
+	// MaxBackoff is the longest delay between restarts. A component that runs for at
+	// least MaxBackoff before returning is no longer restarting in a row and its
+	// delay is reset to Backoff. Defaults to 1 minute.
+	MaxBackoff time.Duration
+}
+
+// Supervisor runs components concurrently under one xmain lifecycle.
+//
+//	sup := &xmain.Supervisor{
+//		Components: []xmain.Component{
+//			{Name: "http", Run: serveHTTP},
+//			{Name: "metrics", Run: serveMetrics},
+//			{Name: "worker", Run: work, Restart: xmain.RestartOnFailure},
+//		},
+//	}
+//	xmain.Main(sup.Main)
+//
+// When a component fails for good, the context of all others is canceled and Main
+// returns once they have all returned. On SIGINT or SIGTERM the context passed to
+// Main is canceled which stops all components and their restarts.
+type Supervisor struct {
+	Components []Component
+}
+
+// Main implements RunFunc to run the components.
+//
+// It declares the flags of each component with Component.Flags and parses
+// ms.Opts with Opts.Parse before starting the components.
+//
+// The returned error combines the errors of all components that failed with
+// multierr. Each is prefixed with the name of its component. Components returning
+// context.Canceled after their context is canceled are not considered failed.
+func (s *Supervisor) Main(ctx context.Context, ms *State) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for _, c := range s.Components {
+		if c.Flags == nil {
+			continue
+		}
+		err := c.Flags(ms.Opts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", c.Name, err)
+		}
+	}
+	err := ms.Opts.Parse()
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	var errs error
+	var wg sync.WaitGroup
+	for _, c := range s.Components {
+		c := c
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := c.run(ctx, ms)
+			if err != nil {
+				mu.Lock()
+				errs = multierr.Append(errs, fmt.Errorf("%s: %w", c.Name, err))
+				mu.Unlock()
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+// run runs the component and restarts it according to its policy until ctx is
+// done. It returns the error that stopped the component.
+func (c Component) run(ctx context.Context, ms *State) error {
+	backoff := c.Backoff
+	if backoff == 0 {
+		backoff = time.Second
+	}
+	maxBackoff := c.MaxBackoff
+	if maxBackoff == 0 {
+		maxBackoff = time.Minute
+	}
+
+	log := ms.Log.WithCCPrefix(c.Name)
+	delay := backoff
+	restarts := 0
+	for {
+		start := time.Now()
+		rs := ms.component()
+		err := c.Run(ctx, rs)
+		if ctx.Err() != nil {
+			if errors.Is(err, context.Canceled) {
+				return nil
+			}
+			return err
+		}
+		if (err == nil && c.Restart != RestartAlways) || (err != nil && c.Restart == RestartNever) {
+			return err
+		}
+
+		if time.Since(start) >= maxBackoff {
+			delay = backoff
+			restarts = 0
+		}
+		if c.MaxRestarts > 0 && restarts >= c.MaxRestarts {
+			if err == nil {
+				return nil
+			}
+			return fmt.Errorf("failed after %d restarts: %w", restarts, err)
+		}
+		restarts++
+		ms.dropHooks(rs)
+
+		if err != nil {
+			log.Warn.Printf("failed: %v: restarting in %v", err, delay)
+		} else {
+			log.Info.Printf("returned: restarting in %v", delay)
+		}
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil
+		case <-t.C:
+		}
+		delay *= 2
+		if delay > maxBackoff {
+			delay = maxBackoff
+		}
+	}
+}
+
+// component returns a copy of ms for one run of a Component. Hooks registered on the
+// copy are registered on ms and may be dropped with dropHooks.
+func (ms *State) component() *State {
+	return &State{
+		Name:   ms.Name,
+		Stdin:  ms.Stdin,
+		Stdout: ms.Stdout,
+		Stderr: ms.Stderr,
+		Log:    ms.Log,
+		Env:    ms.Env,
+		Opts:   ms.Opts,
+		Cmd:    ms.Cmd,
+		PWD:    ms.PWD,
+		parent: ms,
+	}
+}
+
+// dropHooks removes the shutdown and signal hooks registered on owner or a copy of it.
+func (ms *State) dropHooks(owner *State) {
+	ms = ms.root()
+
+	ms.shutdownMu.Lock()
+	hooks := ms.hooks[:0]
+	for _, h := range ms.hooks {
+		if !h.owner.copyOf(owner) {
+			hooks = append(hooks, h)
+		}
+	}
+	ms.hooks = hooks
+	ms.shutdownMu.Unlock()
+
+	ms.signalMu.Lock()
+	for sig, shooks := range ms.signalHooks {
+		var kept []signalHook
+		for _, h := range shooks {
+			if !h.owner.copyOf(owner) {
+				kept = append(kept, h)
+			}
+		}
+		ms.signalHooks[sig] = kept
+	}
+	ms.signalMu.Unlock()
+}
+
+// copyOf reports whether ms is s or was copied from it.
+func (ms *State) copyOf(s *State) bool {
+	for ; ms != nil; ms = ms.parent {
+		if ms == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,313 @@
+package xmain_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"oss.terrastruct.com/util-go/assert"
+	"oss.terrastruct.com/util-go/xmain"
+)
+
+func TestSupervisor(t *testing.T) {
+	t.Parallel()
+
+	serve := func(ctx context.Context, ms *xmain.State) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	t.Run("failure", func(t *testing.T) {
+		t.Parallel()
+
+		sup := &xmain.Supervisor{
+			Components: []xmain.Component{
+				{Name: "http", Run: serve},
+				{Name: "metrics", Run: serve},
+				{Name: "worker", Run: func(ctx context.Context, ms *xmain.State) error {
+					return errors.New("queue closed")
+				}},
+			},
+		}
+		ctx := context.Background()
+		ts := &xmain.TestState{
+			Run:  sup.Main,
+			Args: []string{"sup"},
+		}
+		ts.Start(t, ctx)
+		defer ts.Cleanup(t)
+
+		err := ts.Wait(ctx)
+		assert.ErrorString(t, err, `failed to wait xmain test: sup: worker: queue closed`)
+	})
+
+	t.Run("restart", func(t *testing.T) {
+		t.Parallel()
+
+		var runs int32
+		sup := &xmain.Supervisor{
+			Components: []xmain.Component{
+				{Name: "http", Run: serve},
+				{
+					Name: "worker",
+					Run: func(ctx context.Context, ms *xmain.State) error {
+						atomic.AddInt32(&runs, 1)
+						return errors.New("queue closed")
+					},
+					Restart:     xmain.RestartOnFailure,
+					MaxRestarts: 2,
+					Backoff:     time.Millisecond,
+				},
+			},
+		}
+		stderr := &strings.Builder{}
+		ctx := context.Background()
+		ts := &xmain.TestState{
+			Run:    sup.Main,
+			Args:   []string{"sup"},
+			Stderr: stderr,
+		}
+		ts.Start(t, ctx)
+		defer ts.Cleanup(t)
+
+		err := ts.Wait(ctx)
+		assert.ErrorString(t, err, `failed to wait xmain test: sup: worker: failed after 2 restarts: queue closed`)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&runs))
+		assert.String(t, `worker: warn: failed: queue closed: restarting in 1ms
+worker: warn: failed: queue closed: restarting in 2ms
+`, stderr.String())
+	})
+
+	t.Run("flags", func(t *testing.T) {
+		t.Parallel()
+
+		var port *int64
+		var queue *string
+		var runs int32
+		sup := &xmain.Supervisor{
+			Components: []xmain.Component{
+				{
+					Name: "http",
+					Flags: func(o *xmain.Opts) (err error) {
+						port, err = o.Int64("", "port", "", 8080, "port to listen on")
+						return err
+					},
+					Run: func(ctx context.Context, ms *xmain.State) error {
+						fmt.Fprintf(ms.Stdout, "port=%d\n", *port)
+						return serve(ctx, ms)
+					},
+				},
+				{
+					Name: "worker",
+					Flags: func(o *xmain.Opts) error {
+						queue = o.String("", "queue", "", "jobs", "queue to work on")
+						return nil
+					},
+					Run: func(ctx context.Context, ms *xmain.State) error {
+						atomic.AddInt32(&runs, 1)
+						return fmt.Errorf("queue %s closed", *queue)
+					},
+					Restart:     xmain.RestartOnFailure,
+					MaxRestarts: 2,
+					Backoff:     time.Millisecond,
+				},
+			},
+		}
+		stdout := &strings.Builder{}
+		ctx := context.Background()
+		ts := &xmain.TestState{
+			Run:    sup.Main,
+			Args:   []string{"sup", "--port=80", "--queue=emails"},
+			Stdout: stdout,
+			Stderr: &strings.Builder{},
+		}
+		ts.Start(t, ctx)
+		defer ts.Cleanup(t)
+
+		err := ts.Wait(ctx)
+		assert.ErrorString(t, err, `failed to wait xmain test: sup: worker: failed after 2 restarts: queue emails closed`)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&runs))
+		assert.String(t, "port=80\n", stdout.String())
+	})
+
+	t.Run("persistent", func(t *testing.T) {
+		t.Parallel()
+
+		var verbose *bool
+		var got int32
+		worker := xmain.Component{
+			Name: "worker",
+			Run: func(ctx context.Context, ms *xmain.State) error {
+				if *verbose {
+					atomic.AddInt32(&got, 1)
+				}
+				return nil
+			},
+		}
+		root := &xmain.Command{
+			PersistentFlags: func(o *xmain.Opts) (err error) {
+				verbose, err = o.Bool("", "verbose", "v", false, "")
+				return err
+			},
+			Commands: []*xmain.Command{
+				{
+					Name: "run",
+					Run: (&xmain.Supervisor{
+						Components: []xmain.Component{worker, worker, worker},
+					}).Main,
+				},
+			},
+		}
+		ctx := context.Background()
+		ts := &xmain.TestState{
+			Run:  root.Main,
+			Args: []string{"tool", "run", "-v"},
+		}
+		ts.Start(t, ctx)
+		defer ts.Cleanup(t)
+
+		err := ts.Wait(ctx)
+		assert.Success(t, err)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&got))
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		t.Parallel()
+
+		var mu sync.Mutex
+		var reloaded, closed []int
+		var runs int32
+		started := make(chan struct{})
+		reloadedc := make(chan struct{})
+		sup := &xmain.Supervisor{
+			Components: []xmain.Component{
+				{
+					Name: "worker",
+					Run: func(ctx context.Context, ms *xmain.State) error {
+						run := int(atomic.AddInt32(&runs, 1))
+						ms.OnReload("config", func(context.Context) error {
+							mu.Lock()
+							reloaded = append(reloaded, run)
+							mu.Unlock()
+							close(reloadedc)
+							return nil
+						})
+						ms.OnShutdown("conn", 0, func(context.Context) error {
+							mu.Lock()
+							closed = append(closed, run)
+							mu.Unlock()
+							return nil
+						})
+						if run < 3 {
+							return errors.New("connection lost")
+						}
+						close(started)
+						return serve(ctx, ms)
+					},
+					Restart: xmain.RestartOnFailure,
+					Backoff: time.Millisecond,
+				},
+			},
+		}
+		ctx := context.Background()
+		ts := &xmain.TestState{
+			Run:    sup.Main,
+			Args:   []string{"sup"},
+			Stderr: &strings.Builder{},
+		}
+		ts.Start(t, ctx)
+		defer ts.Cleanup(t)
+		<-started
+
+		err := ts.Signal(ctx, syscall.SIGHUP)
+		assert.Success(t, err)
+		<-reloadedc
+		err = ts.Signal(ctx, syscall.SIGTERM)
+		assert.Success(t, err)
+		err = ts.Wait(ctx)
+		assert.Success(t, err)
+
+		// Only the hooks of the latest run are left.
+		mu.Lock()
+		defer mu.Unlock()
+		assert.StringJSON(t, `[
+  3
+]`, reloaded)
+		assert.StringJSON(t, `[
+  3
+]`, closed)
+	})
+
+	t.Run("hooks", func(t *testing.T) {
+		t.Parallel()
+
+		var closed int32
+		sup := &xmain.Supervisor{
+			Components: []xmain.Component{
+				{Name: "db", Run: func(ctx context.Context, ms *xmain.State) error {
+					ms.OnShutdown("db", 0, func(context.Context) error {
+						atomic.StoreInt32(&closed, 1)
+						return nil
+					})
+					return nil
+				}},
+			},
+		}
+		ctx := context.Background()
+		ts := &xmain.TestState{
+			Run:  sup.Main,
+			Args: []string{"sup"},
+		}
+		ts.Start(t, ctx)
+		defer ts.Cleanup(t)
+
+		err := ts.Wait(ctx)
+		assert.Success(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&closed))
+	})
+
+	t.Run("signal", func(t *testing.T) {
+		t.Parallel()
+
+		var runs int32
+		started := make(chan struct{})
+		sup := &xmain.Supervisor{
+			Components: []xmain.Component{
+				{Name: "http", Run: serve},
+				{
+					Name: "worker",
+					Run: func(ctx context.Context, ms *xmain.State) error {
+						if atomic.AddInt32(&runs, 1) == 1 {
+							return errors.New("queue closed")
+						}
+						close(started)
+						return serve(ctx, ms)
+					},
+					Restart: xmain.RestartAlways,
+					Backoff: time.Millisecond,
+				},
+			},
+		}
+		ctx := context.Background()
+		ts := &xmain.TestState{
+			Run:    sup.Main,
+			Args:   []string{"sup"},
+			Stderr: &strings.Builder{},
+		}
+		ts.Start(t, ctx)
+		defer ts.Cleanup(t)
+		<-started
+
+		err := ts.Signal(ctx, syscall.SIGTERM)
+		assert.Success(t, err)
+		err = ts.Wait(ctx)
+		assert.Success(t, err)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&runs))
+	})
+}
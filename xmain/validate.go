@@ -0,0 +1,173 @@
+package xmain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// Parse parses o.Args into o.Flags and then validates the options with the
+// constraints declared by Required, MutuallyExclusive, AtLeastOne, Validate and
+// Bind in the order they were declared.
+//
+// Parse declares --help and --version unless the program declared flags with those
+// names. pflag.ErrHelp is returned for --help. --version returns an error that has
+// State.Main print the version and exit 0. All other errors are a UsageError so that
+// the hint to run with --help is printed.
+func (o *Opts) Parse() error {
+	var help, version *bool
+	if o.Flags.Lookup("help") == nil {
+		help = o.builtinHelp()
+	}
+	if o.Flags.Lookup("version") == nil {
+		version = o.Flags.Bool("version", false, "print version and exit")
+	}
+
+	err := o.Flags.Parse(o.Args)
+	if err != nil {
+		if errors.Is(err, pflag.ErrHelp) {
+			return err
+		}
+		return UsageErrorf("%v", err)
+	}
+	switch {
+	case help != nil && *help:
+		return pflag.ErrHelp
+	case version != nil && *version:
+		return errVersion
+	}
+	for _, v := range o.validators {
+		err = v(o)
+		if err != nil {
+			var uerr UsageError
+			if errors.As(err, &uerr) {
+				return err
+			}
+			return UsageErrorf("%v", err)
+		}
+	}
+	return nil
+}
+
+// errVersion is returned by Opts.Parse for --version.
+var errVersion = errors.New("version requested")
+
+// builtinHelp declares --help with the -h shorthand if it is free.
+func (o *Opts) builtinHelp() *bool {
+	if o.Flags.ShorthandLookup("h") == nil {
+		return o.Flags.BoolP("help", "h", false, "print usage and exit")
+	}
+	return o.Flags.Bool("help", false, "print usage and exit")
+}
+
+// Required marks flags as required. Parse reports a UsageError if one is not set by
+// a flag, its environment variable or the config file.
+//
+// Required flags are marked as such in the help.
+func (o *Opts) Required(flags ...string) {
+	for _, flag := range flags {
+		flag := flag
+		o.required[flag] = true
+		o.validators = append(o.validators, func(o *Opts) error {
+			if !o.isSet(flag) {
+				return o.missingFlag(flag)
+			}
+			return nil
+		})
+	}
+}
+
+// MutuallyExclusive declares that at most one of flags may be set.
+// e.g. o.MutuallyExclusive("json", "yaml")
+func (o *Opts) MutuallyExclusive(flags ...string) {
+	o.addGroup(flagGroup{flags: flags}, func(set []string) error {
+		if len(set) > 1 {
+			return UsageErrorf("%s cannot be used together", joinFlags(set, "and"))
+		}
+		return nil
+	})
+}
+
+// AtLeastOne declares that one or more of flags must be set.
+func (o *Opts) AtLeastOne(flags ...string) {
+	o.addGroup(flagGroup{flags: flags, atLeastOne: true}, func(set []string) error {
+		if len(set) == 0 {
+			return UsageErrorf("at least one of %s is required", joinFlags(flags, "or"))
+		}
+		return nil
+	})
+}
+
+// Validate adds fn to be run by Parse after the flags are parsed. Errors from fn
+// that are not a UsageError are wrapped in one.
+//
+//	o.Validate(func() error {
+//		if *port == 0 && *socket == "" {
+//			return errors.New("--socket is required when --port is 0")
+//		}
+//		return nil
+//	})
+func (o *Opts) Validate(fn func() error) {
+	o.validators = append(o.validators, func(*Opts) error {
+		return fn()
+	})
+}
+
+// flagGroup is a group of flags declared by MutuallyExclusive or AtLeastOne.
+type flagGroup struct {
+	flags      []string
+	atLeastOne bool
+}
+
+// String describes g for the help.
+func (g flagGroup) String() string {
+	if g.atLeastOne {
+		return fmt.Sprintf("at least one of %s is required", joinFlags(g.flags, "or"))
+	}
+	return fmt.Sprintf("at most one of %s may be set", joinFlags(g.flags, "or"))
+}
+
+func (o *Opts) addGroup(g flagGroup, validate func(set []string) error) {
+	o.groups = append(o.groups, g)
+	o.validators = append(o.validators, func(o *Opts) error {
+		var set []string
+		for _, flag := range g.flags {
+			if o.isSet(flag) {
+				set = append(set, flag)
+			}
+		}
+		return validate(set)
+	})
+}
+
+// isSet reports whether flag was set by a flag, its environment variable or the
+// config file.
+func (o *Opts) isSet(flag string) bool {
+	f := o.Flags.Lookup(flag)
+	if f == nil {
+		return false
+	}
+	return f.Changed || o.sources[flag] != ""
+}
+
+// missingFlag returns the UsageError for the required flag that was not set.
+func (o *Opts) missingFlag(flag string) error {
+	if env, ok := o.flagEnv[flag]; ok {
+		return UsageErrorf("missing required flag --%s or $%s", flag, env)
+	}
+	return UsageErrorf("missing required flag --%s", flag)
+}
+
+// joinFlags formats flags like "--a, --b or --c".
+func joinFlags(flags []string, conj string) string {
+	s := make([]string, len(flags))
+	for i, f := range flags {
+		s[i] = "--" + f
+	}
+	if len(s) == 1 {
+		return s[0]
+	}
+	return strings.Join(s[:len(s)-1], ", ") + " " + conj + " " + s[len(s)-1]
+}
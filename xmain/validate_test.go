@@ -0,0 +1,146 @@
+package xmain_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"oss.terrastruct.com/util-go/assert"
+	"oss.terrastruct.com/util-go/xmain"
+	"oss.terrastruct.com/util-go/xos"
+)
+
+func declareFormats(o *xmain.Opts) error {
+	o.String("TOKEN", "token", "", "", "API token")
+	_, err := o.Bool("", "json", "", false, "output JSON")
+	if err != nil {
+		return err
+	}
+	_, err = o.Bool("", "yaml", "", false, "output YAML")
+	if err != nil {
+		return err
+	}
+	port, err := o.Int64("", "port", "", 0, "port to listen on")
+	if err != nil {
+		return err
+	}
+	socket := o.String("", "socket", "", "", "socket to listen on")
+
+	o.Required("token")
+	o.MutuallyExclusive("json", "yaml")
+	o.AtLeastOne("port", "socket")
+	o.Validate(func() error {
+		if *port != 0 && *socket != "" {
+			return errors.New("--socket cannot be used with a non-zero --port")
+		}
+		return nil
+	})
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	tca := []struct {
+		name    string
+		environ []string
+		args    []string
+		exp     string
+	}{
+		{
+			name: "success",
+			args: []string{"--token=x", "--json", "--port=80"},
+		},
+		{
+			name:    "required_env",
+			environ: []string{"TOKEN=x"},
+			args:    []string{"--socket=/tmp/d2.sock"},
+		},
+		{
+			name: "required",
+			args: []string{"--port=80"},
+			exp:  `bad usage: missing required flag --token or $TOKEN`,
+		},
+		{
+			name: "exclusive",
+			args: []string{"--token=x", "--port=80", "--json", "--yaml"},
+			exp:  `bad usage: --json and --yaml cannot be used together`,
+		},
+		{
+			name: "at_least_one",
+			args: []string{"--token=x"},
+			exp:  `bad usage: at least one of --port or --socket is required`,
+		},
+		{
+			name: "custom",
+			args: []string{"--token=x", "--port=80", "--socket=/tmp/d2.sock"},
+			exp:  `bad usage: --socket cannot be used with a non-zero --port`,
+		},
+		{
+			name: "parse",
+			args: []string{"--port=x"},
+			exp:  `bad usage: invalid argument "x" for "--port" flag: strconv.ParseInt: parsing "x": invalid syntax`,
+		},
+	}
+	for _, tc := range tca {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			o := xmain.NewOpts(xos.NewEnv(tc.environ), tc.args)
+			err := declareFormats(o)
+			assert.Success(t, err)
+			err = o.Parse()
+			if tc.exp == "" {
+				assert.Success(t, err)
+				return
+			}
+			assert.ErrorString(t, err, tc.exp)
+			var uerr xmain.UsageError
+			if !errors.As(err, &uerr) {
+				t.Fatalf("expected UsageError: %#v", err)
+			}
+		})
+	}
+}
+
+func TestValidateHelp(t *testing.T) {
+	t.Parallel()
+
+	stdout := &strings.Builder{}
+	ts := &xmain.TestState{
+		Run: func(ctx context.Context, ms *xmain.State) error {
+			err := declareFormats(ms.Opts)
+			if err != nil {
+				return err
+			}
+			return ms.Opts.Parse()
+		},
+		Env:    xos.NewEnv(nil),
+		Args:   []string{"tool", "--help"},
+		Stdout: stdout,
+	}
+	ctx := context.Background()
+	ts.Start(t, ctx)
+	defer ts.Cleanup(t)
+
+	err := ts.Wait(ctx)
+	assert.Success(t, err)
+	assert.String(t, `Usage:
+  tool [flags]
+
+Flags:
+      --token string    $TOKEN  API token (required) (default "")
+      --json                    output JSON (default false)
+      --yaml                    output YAML (default false)
+      --port int                port to listen on (default 0)
+      --socket string           socket to listen on (default "")
+  -h, --help                    print usage and exit (default false)
+      --version                 print version and exit (default false)
+
+Flag groups:
+  at most one of --json or --yaml may be set
+  at least one of --port or --socket is required
+`, stdout.String())
+}
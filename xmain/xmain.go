@@ -11,15 +11,23 @@ import (
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"oss.terrastruct.com/util-go/cmdlog"
 	"oss.terrastruct.com/util-go/xos"
+	"oss.terrastruct.com/util-go/xterm"
 )
 
 type RunFunc func(context.Context, *State) error
 
+// Main runs run with a State for the process and exits once it returns.
+//
+// The first SIGINT or SIGTERM cancels the context passed to run and runs the
+// shutdown hooks. See State.OnShutdown. SIGHUP, SIGUSR1 and SIGUSR2 are only
+// subscribed to once a hook is registered for them with State.OnSignal so that
+// otherwise they keep their default behaviour of terminating the process.
 func Main(run RunFunc) {
 	name := ""
 	args := []string(nil)
@@ -39,20 +47,28 @@ func Main(run RunFunc) {
 	}
 	ms.Log = cmdlog.New(ms.Env, ms.Stderr)
 	ms.Opts = NewOpts(ms.Env, args)
+	if size, err := xterm.Size(ms.Stdout); err == nil {
+		ms.Opts.Width = size.Width
+	}
 
 	wd, err := os.Getwd()
 	if err != nil {
 		ms.mainFatal(err)
 	}
 	ms.PWD = wd
+	ms.Opts.pwd = wd
 
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	ms.notify = func(sig os.Signal) {
+		signal.Notify(sigs, sig)
+	}
 
 	err = ms.Main(context.Background(), sigs, run)
 	if err != nil {
 		ms.mainFatal(err)
 	}
+	ms.Log.Close()
 }
 
 func (ms *State) mainFatal(err error) {
@@ -69,7 +85,7 @@ func (ms *State) mainFatal(err error) {
 		msg = err.Error()
 		usage = true
 	} else {
-		msg = err.Error()
+		msg = ms.Log.RenderError(err)
 	}
 
 	if msg != "" {
@@ -78,6 +94,7 @@ func (ms *State) mainFatal(err error) {
 			ms.Log.Error.Print("Run with --help to see usage.")
 		}
 	}
+	ms.Log.Close()
 	os.Exit(code)
 }
 
@@ -92,42 +109,87 @@ type State struct {
 	Env  *xos.Env
 	Opts *Opts
 
+	// Cmd is the command being run when dispatching with Command.Main.
+	Cmd *Command
+
 	PWD string
+
+	shutdownMu      sync.Mutex
+	shutdownTimeout time.Duration
+	hooks           []shutdownHook
+
+	signalMu      sync.Mutex
+	signalHooks   map[os.Signal][]signalHook
+	signalPending []os.Signal
+	// signalWake wakes runSignalHooks and signalDone is closed once it returns.
+	signalWake chan struct{}
+	signalDone chan struct{}
+
+	// notify subscribes Main to a signal once a hook is registered for it. It is nil
+	// in tests where signals are sent with TestState.Signal.
+	notify func(os.Signal)
+
+	// parent is the State this State was copied from for a Supervisor component.
+	// Hooks are registered on the root State as that is the one Main runs.
+	parent *State
+}
+
+func (ms *State) root() *State {
+	for ms.parent != nil {
+		ms = ms.parent
+	}
+	return ms
 }
 
 func (ms *State) Main(ctx context.Context, sigs <-chan os.Signal, run func(context.Context, *State) error) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	ms.signalWake = make(chan struct{}, 1)
+	ms.signalDone = make(chan struct{})
+	go ms.runSignalHooks(ctx)
+
 	done := make(chan error, 1)
 	go func() {
 		defer close(done)
-		done <- run(ctx, ms)
+		done <- ms.runBuiltin(ctx, run)
 	}()
 
-	select {
-	case err := <-done:
-		return err
-	case sig := <-sigs:
-		ms.Log.Warn.Printf("received signal %v: shutting down...", sig)
-		cancel()
+	var sig os.Signal
+	for sig == nil {
 		select {
 		case err := <-done:
-			if err != nil && !errors.Is(err, context.Canceled) {
-				return fmt.Errorf("failed to shutdown: %w", err)
+			close(ms.signalWake)
+			_, ok, serr := ms.shutdown(ctx, sigs, nil)
+			if serr != nil {
+				return serr
 			}
-			if sig == syscall.SIGTERM {
-				// We successfully shutdown.
-				return nil
+			if err == nil && !ok {
+				return ExitError{Code: 1}
 			}
-			return ExitError{Code: 1}
-		case <-time.After(time.Minute):
-			return ExitError{
-				Code:    1,
-				Message: "took longer than 1 minute to shutdown: exiting forcefully",
+			return err
+		case s := <-sigs:
+			if !ms.handleSignal(s) {
+				sig = s
 			}
 		}
 	}
+
+	ms.Log.Warn.Printf("received signal %v: shutting down...", sig)
+	cancel()
+	close(ms.signalWake)
+	err, ok, serr := ms.shutdown(ctx, sigs, done)
+	if serr != nil {
+		return serr
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("failed to shutdown: %w", err)
+	}
+	if sig == syscall.SIGTERM && ok {
+		// We successfully shutdown.
+		return nil
+	}
+	return ExitError{Code: 1}
 }
 
 type ExitError struct {
@@ -215,12 +277,28 @@ func (ms *State) AtomicWritePath(fp string, p []byte) error {
 	return nil
 }
 
+// NewTable returns a table that renders to Stdout.
+// See xterm.Table.
+func (ms *State) NewTable(headers ...string) *xterm.Table {
+	return xterm.NewTable(ms.Env, ms.Stdout, headers...)
+}
+
+// NewPager returns a writer to Stdout that pipes long output through $PAGER.
+// Close it once all output is written. See xterm.Pager.
+func (ms *State) NewPager() *xterm.Pager {
+	return xterm.NewPager(ms.Env, ms.Stdout)
+}
+
 // AbsPath joins the PWD with fp to give the absolute path to fp.
 func (ms *State) AbsPath(fp string) string {
+	return absPath(ms.PWD, fp)
+}
+
+func absPath(pwd, fp string) string {
 	if fp == "-" || filepath.IsAbs(fp) {
 		return fp
 	}
-	return filepath.Join(ms.PWD, fp)
+	return filepath.Join(pwd, fp)
 }
 
 // HumanPath makes absolute path fp more suitable for human consumption
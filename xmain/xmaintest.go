@@ -14,6 +14,7 @@ import (
 	"oss.terrastruct.com/util-go/cmdlog"
 	"oss.terrastruct.com/util-go/xdefer"
 	"oss.terrastruct.com/util-go/xos"
+	"oss.terrastruct.com/util-go/xterm"
 )
 
 type TestState struct {
@@ -80,6 +81,7 @@ func (ts *TestState) Start(tb testing.TB, ctx context.Context) {
 		Opts: NewOpts(ts.Env, args),
 		PWD:  ts.PWD,
 	}
+	ts.ms.Opts.pwd = ts.PWD
 
 	if ts.Stdin == nil {
 		ts.ms.Stdin = io.LimitReader(nil, 0)
@@ -122,10 +124,14 @@ func (ts *TestState) Start(tb testing.TB, ctx context.Context) {
 		}()
 	}
 	ts.ms.Log = cmdlog.New(ts.ms.Env, ts.ms.Stderr)
+	if size, err := xterm.Size(ts.ms.Stdout); err == nil {
+		ts.ms.Opts.Width = size.Width
+	}
 
 	go func() {
 		var err error
 		defer func() {
+			ts.ms.Log.Close()
 			ts.closeStdin()
 			ts.ms.Stdout.Close()
 			ts.ms.Stderr.Close()
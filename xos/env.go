@@ -49,6 +49,13 @@ func (e *Env) Setenv(name, value string) {
 }
 
 func (e *Env) Getenv(name string) string {
+	v, _ := e.Lookup(name)
+	return v
+}
+
+// Lookup is like Getenv but also reports whether name is set so that an empty value
+// can be distinguished from an unset one.
+func (e *Env) Lookup(name string) (string, bool) {
 	e.environMu.RLock()
 	defer e.environMu.RUnlock()
 
@@ -59,10 +66,10 @@ func (e *Env) Getenv(name string) string {
 		}
 		name2 := l[:i]
 		if name == name2 {
-			return l[i+1:]
+			return l[i+1:], true
 		}
 	}
-	return ""
+	return "", false
 }
 
 func (e *Env) Bool(name string) (*bool, error) {
@@ -0,0 +1,5 @@
+// Package xpty runs xmain.RunFuncs and subprocesses attached to a pseudo-terminal so
+// that tests can exercise TTY code paths like prompts, colors and resizing.
+//
+// It is only supported on unix.
+package xpty
@@ -0,0 +1,210 @@
+//go:build !windows
+
+package xpty
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/creack/pty"
+
+	"oss.terrastruct.com/util-go/xmain"
+	"oss.terrastruct.com/util-go/xterm"
+)
+
+// Term is a pseudo-terminal pair. Programs are attached to the TTY end while the
+// test drives the terminal end by writing keystrokes and reading the output.
+//
+// Output is read back as processed by the line discipline, so a newline written
+// by a program is read as \r\n unless the program puts the TTY in raw mode.
+type Term struct {
+	ptmx *os.File
+	tty  *os.File
+
+	mu      sync.Mutex
+	out     bytes.Buffer
+	readErr error
+	// changed is closed and replaced whenever out changes or reading ends.
+	changed chan struct{}
+	done    chan struct{}
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Open allocates a pseudo-terminal of the given size. It is closed on tb.Cleanup.
+func Open(tb testing.TB, size xterm.WinSize) *Term {
+	tb.Helper()
+
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		tb.Fatalf("failed to open pty: %v", err)
+	}
+	t := &Term{
+		ptmx:    ptmx,
+		tty:     tty,
+		changed: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	tb.Cleanup(func() {
+		t.Close()
+	})
+
+	err = t.Resize(size)
+	if err != nil {
+		tb.Fatalf("failed to resize pty: %v", err)
+	}
+
+	go t.read()
+	return t
+}
+
+func (t *Term) read() {
+	defer close(t.done)
+
+	b := make([]byte, 4096)
+	for {
+		n, err := t.ptmx.Read(b)
+		t.mu.Lock()
+		t.out.Write(b[:n])
+		if err != nil {
+			t.readErr = err
+		}
+		close(t.changed)
+		t.changed = make(chan struct{})
+		t.mu.Unlock()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// TTY returns the TTY end of the terminal.
+// Writes to it appear in Output and reads from it receive what is written with Write.
+func (t *Term) TTY() *os.File {
+	return t.tty
+}
+
+// Write writes keystrokes to the terminal as if typed by a user.
+func (t *Term) Write(p []byte) (int, error) {
+	return t.ptmx.Write(p)
+}
+
+// WriteString is like Write but for strings.
+func (t *Term) WriteString(s string) (int, error) {
+	return t.ptmx.WriteString(s)
+}
+
+// Output returns everything written to the TTY so far.
+func (t *Term) Output() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.out.String()
+}
+
+// Expect waits until Output contains s.
+func (t *Term) Expect(ctx context.Context, s string) error {
+	for {
+		t.mu.Lock()
+		out := t.out.String()
+		readErr := t.readErr
+		changed := t.changed
+		t.mu.Unlock()
+
+		if strings.Contains(out, s) {
+			return nil
+		}
+		if readErr != nil {
+			return fmt.Errorf("terminal closed before output contained %q: %w; output: %q", s, readErr, out)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for output to contain %q: %w; output: %q", s, ctx.Err(), out)
+		case <-changed:
+		}
+	}
+}
+
+// Resize sets the size of the terminal.
+//
+// The kernel sends SIGWINCH to subprocesses started with Command. As programs run
+// with Start share the test process, SIGWINCH is sent to it as well so that
+// xterm.NotifySize observes the change.
+func (t *Term) Resize(size xterm.WinSize) error {
+	err := pty.Setsize(t.ptmx, &pty.Winsize{
+		Cols: uint16(size.Width),
+		Rows: uint16(size.Height),
+	})
+	if err != nil {
+		return err
+	}
+	return syscall.Kill(syscall.Getpid(), syscall.SIGWINCH)
+}
+
+// Close closes both ends of the terminal and waits for the output to be read.
+func (t *Term) Close() error {
+	t.closeOnce.Do(func() {
+		t.closeErr = t.tty.Close()
+		err := t.ptmx.Close()
+		if t.closeErr == nil {
+			t.closeErr = err
+		}
+		<-t.done
+	})
+	return t.closeErr
+}
+
+// Start starts ts with its stdin, stdout and stderr attached to the terminal.
+// Call Wait and Cleanup on ts as usual.
+func (t *Term) Start(tb testing.TB, ctx context.Context, ts *xmain.TestState) {
+	tb.Helper()
+
+	f := ttyFile{t.tty}
+	ts.Stdin = f
+	ts.Stdout = f
+	ts.Stderr = f
+	ts.Start(tb, ctx)
+}
+
+// Command returns a command attached to the terminal as its controlling terminal.
+func (t *Term) Command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = t.tty
+	cmd.Stdout = t.tty
+	cmd.Stderr = t.tty
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setsid:  true,
+		Setctty: true,
+	}
+	return cmd
+}
+
+// ttyFile prevents xmain.TestState from closing the TTY when the program exits
+// while still allowing it to be detected as a terminal.
+type ttyFile struct {
+	f *os.File
+}
+
+func (f ttyFile) Read(p []byte) (int, error) {
+	return f.f.Read(p)
+}
+
+func (f ttyFile) Write(p []byte) (int, error) {
+	return f.f.Write(p)
+}
+
+func (f ttyFile) Fd() uintptr {
+	return f.f.Fd()
+}
+
+func (f ttyFile) Close() error {
+	return nil
+}
@@ -0,0 +1,75 @@
+//go:build !windows
+
+package xpty_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"oss.terrastruct.com/util-go/assert"
+	"oss.terrastruct.com/util-go/xmain"
+	"oss.terrastruct.com/util-go/xos"
+	"oss.terrastruct.com/util-go/xpty"
+	"oss.terrastruct.com/util-go/xterm"
+)
+
+func TestStart(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	term := xpty.Open(t, xterm.WinSize{Width: 80, Height: 24})
+	ts := &xmain.TestState{
+		Run: func(ctx context.Context, ms *xmain.State) error {
+			sizes, err := xterm.NotifySize(ctx, ms.Stdout)
+			if err != nil {
+				return err
+			}
+			size := <-sizes
+			fmt.Fprintf(ms.Stdout, "size %dx%d\n", size.Width, size.Height)
+
+			ok, err := xterm.Confirm(ms.Env, ms.Stdin, ms.Stdout, "resize?", false)
+			if err != nil {
+				return err
+			}
+			if ok {
+				size = <-sizes
+				fmt.Fprintf(ms.Stdout, "size %dx%d\n", size.Width, size.Height)
+			}
+			return nil
+		},
+		Env:  xos.NewEnv(nil),
+		Args: []string{"resize"},
+	}
+	term.Start(t, ctx, ts)
+	defer ts.Cleanup(t)
+
+	assert.Success(t, term.Expect(ctx, "size 80x24\r\n"))
+	assert.Success(t, term.Expect(ctx, "\x1b[1mresize?\x1b[0m [y/N] "))
+	_, err := term.WriteString("y\n")
+	assert.Success(t, err)
+	assert.Success(t, term.Resize(xterm.WinSize{Width: 120, Height: 40}))
+	assert.Success(t, term.Expect(ctx, "size 120x40\r\n"))
+	assert.Success(t, ts.Wait(ctx))
+}
+
+func TestCommand(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	term := xpty.Open(t, xterm.WinSize{Width: 80, Height: 24})
+	cmd := term.Command(ctx, "sh", "-c", `stty size; read x; echo "got $x"; stty size`)
+	assert.Success(t, cmd.Start())
+
+	assert.Success(t, term.Expect(ctx, "24 80\r\n"))
+	assert.Success(t, term.Resize(xterm.WinSize{Width: 100, Height: 30}))
+	_, err := term.WriteString("meow\n")
+	assert.Success(t, err)
+	assert.Success(t, term.Expect(ctx, "got meow\r\n30 100\r\n"))
+	assert.Success(t, cmd.Wait())
+}
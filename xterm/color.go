@@ -0,0 +1,260 @@
+package xterm
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"oss.terrastruct.com/util-go/xos"
+)
+
+// Depth is the number of colors a terminal supports.
+type Depth int
+
+const (
+	DepthNone Depth = iota
+	Depth16
+	Depth256
+	DepthTrue
+)
+
+// ColorDepth detects the number of colors supported by w.
+//
+// $COLOR=256 or $COLOR=truecolor force enable color at that depth. Otherwise if
+// ShouldColor, the depth is detected from $COLORTERM and $TERM, defaulting to 16.
+func ColorDepth(env *xos.Env, w io.Writer) Depth {
+	if !ShouldColor(env, w) {
+		return DepthNone
+	}
+	switch env.Getenv("COLOR") {
+	case "256":
+		return Depth256
+	case "truecolor":
+		return DepthTrue
+	}
+	switch env.Getenv("COLORTERM") {
+	case "truecolor", "24bit":
+		return DepthTrue
+	}
+	if strings.Contains(env.Getenv("TERM"), "256color") {
+		return Depth256
+	}
+	return Depth16
+}
+
+// Color256 returns the caps for foreground color n of the 256 color palette.
+func Color256(n uint8) string {
+	return fmt.Sprintf("%s38;5;%dm", csi, n)
+}
+
+// BgColor256 returns the caps for background color n of the 256 color palette.
+func BgColor256(n uint8) string {
+	return fmt.Sprintf("%s48;5;%dm", csi, n)
+}
+
+// RGB returns the caps for a truecolor foreground color.
+func RGB(r, g, b uint8) string {
+	return fmt.Sprintf("%s38;2;%d;%d;%dm", csi, r, g, b)
+}
+
+// BgRGB returns the caps for a truecolor background color.
+func BgRGB(r, g, b uint8) string {
+	return fmt.Sprintf("%s48;2;%d;%d;%dm", csi, r, g, b)
+}
+
+// colors256 are the distinct colors of the 256 color cube that are neither
+// too dark nor gray.
+var colors256 = func() []string {
+	var colors []string
+	levels := []int{1, 3, 5}
+	for _, r := range levels {
+		for _, g := range levels {
+			for _, b := range levels {
+				if r == g && g == b {
+					continue
+				}
+				colors = append(colors, Color256(uint8(16+36*r+6*g+b)))
+			}
+		}
+	}
+	return colors
+}()
+
+// colorsTrue are evenly spaced hues at a readable saturation and lightness.
+var colorsTrue = func() []string {
+	var colors []string
+	for h := 0; h < 360; h += 10 {
+		colors = append(colors, RGB(hslToRGB(float64(h), 0.65, 0.6)))
+	}
+	return colors
+}()
+
+func hslToRGB(h, s, l float64) (uint8, uint8, uint8) {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+
+	var r, g, b float64
+	switch {
+	case h < 60:
+		r, g, b = c, x, 0
+	case h < 120:
+		r, g, b = x, c, 0
+	case h < 180:
+		r, g, b = 0, c, x
+	case h < 240:
+		r, g, b = 0, x, c
+	case h < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return uint8(math.Round((r + m) * 255)), uint8(math.Round((g + m) * 255)), uint8(math.Round((b + m) * 255))
+}
+
+// rgb16 are the default xterm RGB values of the 16 basic colors.
+var rgb16 = [16][3]int{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+func color256ToRGB(n int) (int, int, int) {
+	switch {
+	case n < 16:
+		return rgb16[n][0], rgb16[n][1], rgb16[n][2]
+	case n < 232:
+		n -= 16
+		return cubeLevels[n/36], cubeLevels[n/6%6], cubeLevels[n%6]
+	default:
+		g := 8 + 10*(n-232)
+		return g, g, g
+	}
+}
+
+func rgbDist(r1, g1, b1, r2, g2, b2 int) int {
+	dr, dg, db := r1-r2, g1-g2, b1-b2
+	return dr*dr + dg*dg + db*db
+}
+
+func rgbTo16(r, g, b int) int {
+	best, bestDist := 0, math.MaxInt
+	for i, c := range rgb16 {
+		d := rgbDist(r, g, b, c[0], c[1], c[2])
+		if d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+func rgbTo256(r, g, b int) int {
+	nearestLevel := func(v int) int {
+		best := 0
+		for i, l := range cubeLevels {
+			if abs(v-l) < abs(v-cubeLevels[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	ri, gi, bi := nearestLevel(r), nearestLevel(g), nearestLevel(b)
+	cube := 16 + 36*ri + 6*gi + bi
+
+	gray := 232 + (r+g+b)/3/10
+	if gray > 255 {
+		gray = 255
+	}
+
+	cr, cg, cb := color256ToRGB(cube)
+	gr, gg, gb := color256ToRGB(gray)
+	if rgbDist(r, g, b, gr, gg, gb) < rgbDist(r, g, b, cr, cg, cb) {
+		return gray
+	}
+	return cube
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+var sgrRegex = regexp.MustCompile(`\x1b\[([0-9;]*)m`)
+
+// Downgrade rewrites the 256 and truecolor SGR sequences in caps to their nearest
+// equivalents at depth d.
+func Downgrade(caps string, d Depth) string {
+	if d >= DepthTrue {
+		return caps
+	}
+	return sgrRegex.ReplaceAllStringFunc(caps, func(seq string) string {
+		params := strings.Split(sgrRegex.FindStringSubmatch(seq)[1], ";")
+		var out []string
+		for i := 0; i < len(params); i++ {
+			p := params[i]
+			if (p != "38" && p != "48") || i+1 >= len(params) {
+				out = append(out, p)
+				continue
+			}
+			bg := p == "48"
+
+			var r, g, b int
+			switch params[i+1] {
+			case "5":
+				if i+2 >= len(params) {
+					out = append(out, params[i:]...)
+					i = len(params)
+					continue
+				}
+				n, _ := strconv.Atoi(params[i+2])
+				i += 2
+				if d == Depth256 {
+					out = append(out, p, "5", strconv.Itoa(n))
+					continue
+				}
+				r, g, b = color256ToRGB(n)
+			case "2":
+				if i+4 >= len(params) {
+					out = append(out, params[i:]...)
+					i = len(params)
+					continue
+				}
+				r, _ = strconv.Atoi(params[i+2])
+				g, _ = strconv.Atoi(params[i+3])
+				b, _ = strconv.Atoi(params[i+4])
+				i += 4
+				if d == Depth256 {
+					out = append(out, p, "5", strconv.Itoa(rgbTo256(r, g, b)))
+					continue
+				}
+			default:
+				out = append(out, p)
+				continue
+			}
+
+			out = append(out, strconv.Itoa(sgr16(rgbTo16(r, g, b), bg)))
+		}
+		return csi + strings.Join(out, ";") + "m"
+	})
+}
+
+// sgr16 returns the SGR parameter for color n of the 16 basic colors.
+func sgr16(n int, bg bool) int {
+	base := 30
+	if n >= 8 {
+		base = 90
+		n -= 8
+	}
+	if bg {
+		base += 10
+	}
+	return base + n
+}
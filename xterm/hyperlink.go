@@ -0,0 +1,86 @@
+package xterm
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"oss.terrastruct.com/util-go/xos"
+)
+
+// ShouldHyperlink reports whether hyperlinks should be written to w.
+//
+// $HYPERLINKS is obeyed to force enable/disable. Otherwise hyperlinks are written
+// only if ShouldColor.
+func ShouldHyperlink(env *xos.Env, w io.Writer) bool {
+	eb, err := env.Bool("HYPERLINKS")
+	if eb != nil {
+		return *eb
+	}
+	if err != nil {
+		os.Stderr.WriteString(fmt.Sprintf("xterm: %v", err))
+	}
+	return ShouldColor(env, w)
+}
+
+// Hyperlink returns text linked to url with OSC 8 if ShouldHyperlink.
+// Otherwise text is returned as is. Control characters are removed from url so
+// that it cannot end the escape sequence early.
+//
+// See https://gist.github.com/egmontkob/eb114294efbcd5adb1944c9f3cb5feda
+func Hyperlink(env *xos.Env, w io.Writer, url, text string) string {
+	if !ShouldHyperlink(env, w) {
+		return text
+	}
+	return osc + "8;;" + stripControl(url) + st + text + osc + "8;;" + st
+}
+
+// FileHyperlink returns text linked to the local file at fp with a file:// URL.
+// fp is made absolute relative to the working directory.
+func FileHyperlink(env *xos.Env, w io.Writer, fp, text string) string {
+	if !ShouldHyperlink(env, w) {
+		return text
+	}
+	return Hyperlink(env, w, FileURL(fp), text)
+}
+
+// FileURL returns the file:// URL for the local file at fp including the hostname
+// as recommended for OSC 8 hyperlinks.
+func FileURL(fp string) string {
+	abs, err := filepath.Abs(fp)
+	if err == nil {
+		fp = abs
+	}
+	host, _ := os.Hostname()
+	u := url.URL{
+		Scheme: "file",
+		Host:   host,
+		Path:   filepath.ToSlash(fp),
+	}
+	return u.String()
+}
+
+// SetTitle sets the title of the terminal window to title if ShouldColor.
+// Control characters are removed from title.
+func SetTitle(env *xos.Env, w io.Writer, title string) error {
+	if !ShouldColor(env, w) {
+		return nil
+	}
+	_, err := io.WriteString(w, osc+"2;"+stripControl(title)+st)
+	return err
+}
+
+// stripControl removes the control characters like ESC and BEL from s that would
+// otherwise end an OSC sequence and inject escapes into the terminal.
+func stripControl(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,173 @@
+package xterm
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"oss.terrastruct.com/util-go/xos"
+)
+
+// DefaultPager is used when $PAGER is unset. -R passes colors through.
+const DefaultPager = "less -R"
+
+// Pager is a writer that pipes output through $PAGER when it does not fit on the
+// screen.
+//
+// Output is buffered until it is taller than the terminal w, at which point the
+// pager is started with everything written so far. If w is not a terminal or $PAGER
+// is set but empty, output is written directly to w.
+//
+// If $PAGER fails to start, output is written directly to w.
+//
+// If the pager exits before all output is written, such as when the user quits
+// less, the rest of the output is discarded without error. Close must be called to
+// flush the output and wait for the pager to exit.
+type Pager struct {
+	env  *xos.Env
+	w    io.Writer
+	size WinSize
+
+	// buf holds output until it is known whether it fits on the screen.
+	buf    bytes.Buffer
+	direct bool
+
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	quit  bool
+}
+
+// NewPager returns a Pager writing to w.
+func NewPager(env *xos.Env, w io.Writer) *Pager {
+	p := &Pager{
+		env: env,
+		w:   w,
+	}
+	size, err := Size(w)
+	if pager, _ := p.pager(); err != nil || size.Height <= 0 || pager == "" {
+		p.direct = true
+	}
+	p.size = size
+	return p
+}
+
+func (p *Pager) pager() (string, bool) {
+	pager, ok := p.env.Lookup("PAGER")
+	if !ok {
+		return DefaultPager, false
+	}
+	return strings.TrimSpace(pager), true
+}
+
+func (p *Pager) Write(b []byte) (int, error) {
+	switch {
+	case p.direct:
+		return p.w.Write(b)
+	case p.quit:
+		return len(b), nil
+	case p.stdin != nil:
+		return p.writePager(b)
+	}
+
+	p.buf.Write(b)
+	if p.rows() < p.size.Height {
+		return len(b), nil
+	}
+	err := p.start()
+	if err != nil {
+		// Without a working pager, output is still better than none.
+		p.direct = true
+		_, err = p.w.Write(p.buf.Bytes())
+	} else {
+		_, err = p.writePager(p.buf.Bytes())
+	}
+	p.buf.Reset()
+	if err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// rows returns the number of terminal rows the buffered output occupies.
+func (p *Pager) rows() int {
+	n := 0
+	for _, line := range strings.Split(p.buf.String(), "\n") {
+		w := VisibleWidth(line)
+		if p.size.Width > 0 && w > p.size.Width {
+			n += (w + p.size.Width - 1) / p.size.Width
+		} else {
+			n++
+		}
+	}
+	// The last line is only counted once it is started.
+	if bytes.HasSuffix(p.buf.Bytes(), []byte("\n")) {
+		n--
+	}
+	return n
+}
+
+func (p *Pager) start() error {
+	pager, _ := p.pager()
+	args := strings.Fields(pager)
+	p.cmd = exec.Command(args[0], args[1:]...)
+	p.cmd.Env = p.env.Environ()
+	// If w is an *os.File, the pager writes to the terminal directly.
+	p.cmd.Stdout = p.w
+	p.cmd.Stderr = p.w
+
+	stdin, err := p.cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	err = p.cmd.Start()
+	if err != nil {
+		return err
+	}
+	p.stdin = stdin
+	return nil
+}
+
+func (p *Pager) writePager(b []byte) (int, error) {
+	_, err := p.stdin.Write(b)
+	if err != nil {
+		if isBrokenPipe(err) {
+			p.quit = true
+			return len(b), nil
+		}
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Close writes any buffered output and waits for the pager to exit.
+func (p *Pager) Close() error {
+	if p.direct {
+		return nil
+	}
+	if p.stdin == nil {
+		p.direct = true
+		_, err := p.w.Write(p.buf.Bytes())
+		p.buf.Reset()
+		return err
+	}
+
+	err := p.stdin.Close()
+	if err != nil && !isBrokenPipe(err) {
+		return err
+	}
+	err = p.cmd.Wait()
+	var eerr *exec.ExitError
+	if p.quit && errors.As(err, &eerr) {
+		// Pagers killed by SIGPIPE or that exit early are not an error.
+		return nil
+	}
+	return err
+}
+
+func isBrokenPipe(err error) bool {
+	return errors.Is(err, syscall.EPIPE) || errors.Is(err, os.ErrClosed)
+}
@@ -0,0 +1,77 @@
+//go:build !windows
+
+package xterm_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"oss.terrastruct.com/util-go/assert"
+	"oss.terrastruct.com/util-go/xos"
+	"oss.terrastruct.com/util-go/xpty"
+	"oss.terrastruct.com/util-go/xterm"
+)
+
+func TestPager(t *testing.T) {
+	t.Parallel()
+
+	writeLines := func(t *testing.T, p *xterm.Pager, n int) {
+		for i := 0; i < n; i++ {
+			_, err := fmt.Fprintf(p, "line %d\n", i)
+			assert.Success(t, err)
+		}
+		assert.Success(t, p.Close())
+	}
+
+	t.Run("notTTY", func(t *testing.T) {
+		t.Parallel()
+
+		b := &bytes.Buffer{}
+		writeLines(t, xterm.NewPager(xos.NewEnv(nil), b), 100)
+		assert.Equal(t, 100, strings.Count(b.String(), "\n"))
+	})
+
+	t.Run("short", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		term := xpty.Open(t, xterm.WinSize{Width: 80, Height: 5})
+		env := xos.NewEnv([]string{"PAGER=echo paged"})
+		writeLines(t, xterm.NewPager(env, term.TTY()), 4)
+		assert.Success(t, term.Expect(ctx, "line 0\r\nline 1\r\nline 2\r\nline 3\r\n"))
+	})
+
+	t.Run("long", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		term := xpty.Open(t, xterm.WinSize{Width: 80, Height: 5})
+		env := xos.NewEnv([]string{"PAGER=echo paged"})
+		// The pager exits without reading so most writes hit a broken pipe.
+		writeLines(t, xterm.NewPager(env, term.TTY()), 10000)
+		assert.Success(t, term.Expect(ctx, "paged\r\n"))
+		if strings.Contains(term.Output(), "line 0") {
+			t.Fatalf("expected output to be paged: %q", term.Output())
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		term := xpty.Open(t, xterm.WinSize{Width: 80, Height: 5})
+		env := xos.NewEnv([]string{"PAGER="})
+		writeLines(t, xterm.NewPager(env, term.TTY()), 10)
+		assert.Success(t, term.Expect(ctx, "line 9\r\n"))
+	})
+}
@@ -0,0 +1,352 @@
+package xterm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+
+	"oss.terrastruct.com/util-go/xos"
+)
+
+// ErrInterrupted is returned when a prompt is interrupted with Ctrl+C or Ctrl+D.
+var ErrInterrupted = errors.New("prompt interrupted")
+
+// The prompts below read answers from in and write questions to out.
+//
+// When in is a terminal, Select and MultiSelect are navigated with the arrow keys and
+// Password does not echo. Otherwise answers are read line by line from in so that
+// prompts may be scripted or driven by xmain.TestState pipes. If in ends without an
+// answer, the default is returned or an error if there is none.
+
+// Confirm asks a yes or no question. def is the answer if none is given.
+func Confirm(env *xos.Env, in io.Reader, out io.Writer, question string, def bool) (bool, error) {
+	hint := "[y/N]"
+	if def {
+		hint = "[Y/n]"
+	}
+	for {
+		fmt.Fprintf(out, "%s %s ", promptQuestion(env, out, question), hint)
+		line, err := readLine(in)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				fmt.Fprintln(out)
+				if isTTY(in) {
+					return false, ErrInterrupted
+				}
+				return def, nil
+			}
+			return false, err
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "":
+			return def, nil
+		case "y", "yes":
+			return true, nil
+		case "n", "no":
+			return false, nil
+		}
+		fmt.Fprintln(out, "Please answer y or n.")
+	}
+}
+
+// Select asks to choose one of options and returns its index.
+// def is the index of the default option or -1 for none.
+func Select(env *xos.Env, in io.Reader, out io.Writer, question string, options []string, def int) (int, error) {
+	if len(options) == 0 {
+		return -1, errors.New("no options to select from")
+	}
+	if fd, ok := ttyFd(in); ok {
+		var defs []int
+		if def >= 0 {
+			defs = []int{def}
+		}
+		sel, err := selectTTY(env, fd, in, out, question, options, defs, false)
+		if err != nil {
+			return -1, err
+		}
+		return sel[0], nil
+	}
+
+	printOptions(out, question, options)
+	for {
+		hint := ""
+		if def >= 0 {
+			hint = fmt.Sprintf(" [%d]", def+1)
+		}
+		fmt.Fprintf(out, "Enter a number%s: ", hint)
+		line, err := readLine(in)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				fmt.Fprintln(out)
+				if def >= 0 {
+					return def, nil
+				}
+				return -1, fmt.Errorf("no option selected for %q and input is not a terminal", question)
+			}
+			return -1, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" && def >= 0 {
+			return def, nil
+		}
+		i, ok := parseOption(line, options)
+		if ok {
+			return i, nil
+		}
+		fmt.Fprintf(out, "Please enter a number from 1 to %d.\n", len(options))
+	}
+}
+
+// MultiSelect asks to choose any number of options and returns their indexes.
+// defs are the indexes of the options selected by default.
+func MultiSelect(env *xos.Env, in io.Reader, out io.Writer, question string, options []string, defs []int) ([]int, error) {
+	if len(options) == 0 {
+		return nil, errors.New("no options to select from")
+	}
+	if fd, ok := ttyFd(in); ok {
+		return selectTTY(env, fd, in, out, question, options, defs, true)
+	}
+
+	printOptions(out, question, options)
+	for {
+		hint := make([]string, len(defs))
+		for i, d := range defs {
+			hint[i] = strconv.Itoa(d + 1)
+		}
+		fmt.Fprintf(out, "Enter numbers separated by commas [%s]: ", strings.Join(hint, ","))
+		line, err := readLine(in)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				fmt.Fprintln(out)
+				return defs, nil
+			}
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			return defs, nil
+		}
+		var sel []int
+		for _, part := range strings.Split(line, ",") {
+			i, ok := parseOption(strings.TrimSpace(part), options)
+			if !ok {
+				sel = nil
+				break
+			}
+			sel = append(sel, i)
+		}
+		if sel != nil {
+			return sel, nil
+		}
+		fmt.Fprintf(out, "Please enter numbers from 1 to %d.\n", len(options))
+	}
+}
+
+// Password asks for a secret without echoing it if in is a terminal.
+func Password(env *xos.Env, in io.Reader, out io.Writer, prompt string) (string, error) {
+	fmt.Fprintf(out, "%s ", promptQuestion(env, out, prompt))
+	if fd, ok := ttyFd(in); ok {
+		b, err := term.ReadPassword(fd)
+		fmt.Fprintln(out)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+
+	line, err := readLine(in)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			fmt.Fprintln(out)
+			return "", fmt.Errorf("no answer for %q and input is not a terminal", prompt)
+		}
+		return "", err
+	}
+	return line, nil
+}
+
+func promptQuestion(env *xos.Env, out io.Writer, question string) string {
+	return Tput(env, out, Bold, question)
+}
+
+func printOptions(out io.Writer, question string, options []string) {
+	fmt.Fprintln(out, question)
+	for i, o := range options {
+		fmt.Fprintf(out, "  %d) %s\n", i+1, o)
+	}
+}
+
+// parseOption parses s as the 1-based number of an option or as the option itself.
+func parseOption(s string, options []string) (int, bool) {
+	i, err := strconv.Atoi(s)
+	if err == nil && 1 <= i && i <= len(options) {
+		return i - 1, true
+	}
+	for i, o := range options {
+		if s == o {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+// readLine reads a line from r one byte at a time so that nothing past the line is
+// consumed. It returns io.EOF only if r ends before any byte is read.
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		n, err := r.Read(b)
+		if n > 0 {
+			if b[0] == '\n' {
+				return strings.TrimSuffix(string(line), "\r"), nil
+			}
+			line = append(line, b[0])
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(line) > 0 {
+				return string(line), nil
+			}
+			return "", err
+		}
+	}
+}
+
+type key int
+
+const (
+	keyOther key = iota
+	keyUp
+	keyDown
+	keySpace
+	keyEnter
+	keyInterrupt
+)
+
+func readKey(r io.Reader) (key, error) {
+	b := make([]byte, 1)
+	_, err := io.ReadFull(r, b)
+	if err != nil {
+		return keyOther, err
+	}
+	switch b[0] {
+	case '\r', '\n':
+		return keyEnter, nil
+	case ' ':
+		return keySpace, nil
+	case 'k':
+		return keyUp, nil
+	case 'j':
+		return keyDown, nil
+	case 0x03, 0x04:
+		return keyInterrupt, nil
+	case 0x1b:
+		seq := make([]byte, 2)
+		_, err = io.ReadFull(r, seq)
+		if err != nil {
+			return keyOther, err
+		}
+		if seq[0] == '[' || seq[0] == 'O' {
+			switch seq[1] {
+			case 'A':
+				return keyUp, nil
+			case 'B':
+				return keyDown, nil
+			}
+		}
+	}
+	return keyOther, nil
+}
+
+// selectTTY renders options in raw mode and lets the user navigate them with the
+// arrow keys. When multi, space toggles the option under the cursor.
+func selectTTY(env *xos.Env, fd int, in io.Reader, out io.Writer, question string, options []string, defs []int, multi bool) (_ []int, err error) {
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		rerr := term.Restore(fd, state)
+		if err == nil {
+			err = rerr
+		}
+	}()
+
+	selected := make([]bool, len(options))
+	cursor := 0
+	for i, d := range defs {
+		if 0 <= d && d < len(options) {
+			selected[d] = true
+			if i == 0 {
+				cursor = d
+			}
+		}
+	}
+
+	hint := "(arrow keys to move, enter to select)"
+	if multi {
+		hint = "(arrow keys to move, space to toggle, enter to confirm)"
+	}
+	fmt.Fprintf(out, "%s %s\r\n", promptQuestion(env, out, question), Tput(env, out, Dim, hint))
+
+	draw := func() {
+		for i, o := range options {
+			line := "  "
+			if i == cursor {
+				line = Tput(env, out, Cyan, ">") + " "
+			}
+			if multi {
+				box := "[ ] "
+				if selected[i] {
+					box = "[x] "
+				}
+				line += box
+			}
+			if i == cursor {
+				o = Tput(env, out, Cyan, o)
+			}
+			fmt.Fprintf(out, "\r%s%s\r\n", EraseLine, line+o)
+		}
+	}
+	rewind := func() {
+		fmt.Fprint(out, strings.Repeat(CursorUp, len(options)))
+	}
+
+	draw()
+	for {
+		k, err := readKey(in)
+		if err != nil {
+			return nil, err
+		}
+		switch k {
+		case keyUp:
+			cursor = (cursor - 1 + len(options)) % len(options)
+		case keyDown:
+			cursor = (cursor + 1) % len(options)
+		case keySpace:
+			if multi {
+				selected[cursor] = !selected[cursor]
+			}
+		case keyInterrupt:
+			return nil, ErrInterrupted
+		case keyEnter:
+			var sel []int
+			if multi {
+				for i, s := range selected {
+					if s {
+						sel = append(sel, i)
+					}
+				}
+			} else {
+				sel = []int{cursor}
+			}
+			return sel, nil
+		}
+		rewind()
+		draw()
+	}
+}
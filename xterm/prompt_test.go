@@ -0,0 +1,101 @@
+package xterm_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/creack/pty"
+
+	"oss.terrastruct.com/util-go/assert"
+	"oss.terrastruct.com/util-go/xos"
+	"oss.terrastruct.com/util-go/xterm"
+)
+
+func TestPrompt(t *testing.T) {
+	t.Parallel()
+
+	env := xos.NewEnv(nil)
+
+	t.Run("Confirm", func(t *testing.T) {
+		t.Parallel()
+
+		out := &bytes.Buffer{}
+		in := strings.NewReader("maybe\nYes\n\n")
+		ok, err := xterm.Confirm(env, in, out, "overwrite file?", false)
+		assert.Success(t, err)
+		assert.Equal(t, true, ok)
+		ok, err = xterm.Confirm(env, in, out, "overwrite file?", false)
+		assert.Success(t, err)
+		assert.Equal(t, false, ok)
+		ok, err = xterm.Confirm(env, in, out, "overwrite file?", true)
+		assert.Success(t, err)
+		assert.Equal(t, true, ok)
+		assert.String(t, `overwrite file? [y/N] Please answer y or n.
+overwrite file? [y/N] overwrite file? [y/N] overwrite file? [Y/n] 
+`, out.String())
+	})
+
+	t.Run("Select", func(t *testing.T) {
+		t.Parallel()
+
+		out := &bytes.Buffer{}
+		in := strings.NewReader("4\nblue\n")
+		options := []string{"red", "green", "blue"}
+		i, err := xterm.Select(env, in, out, "Pick a color:", options, -1)
+		assert.Success(t, err)
+		assert.Equal(t, 2, i)
+
+		_, err = xterm.Select(env, in, out, "Pick a color:", options, -1)
+		assert.ErrorString(t, err, `no option selected for "Pick a color:" and input is not a terminal`)
+
+		i, err = xterm.Select(env, in, out, "Pick a color:", options, 1)
+		assert.Success(t, err)
+		assert.Equal(t, 1, i)
+	})
+
+	t.Run("MultiSelect", func(t *testing.T) {
+		t.Parallel()
+
+		out := &bytes.Buffer{}
+		in := strings.NewReader("3, red\n")
+		options := []string{"red", "green", "blue"}
+		sel, err := xterm.MultiSelect(env, in, out, "Pick colors:", options, nil)
+		assert.Success(t, err)
+		assert.StringJSON(t, `[
+  2,
+  0
+]`, sel)
+	})
+
+	t.Run("Password", func(t *testing.T) {
+		t.Parallel()
+
+		out := &bytes.Buffer{}
+		pw, err := xterm.Password(env, strings.NewReader("hunter2\n"), out, "Password:")
+		assert.Success(t, err)
+		assert.Equal(t, "hunter2", pw)
+	})
+
+	t.Run("tty", func(t *testing.T) {
+		t.Parallel()
+
+		ptmx, tty, err := pty.Open()
+		if err != nil {
+			t.Fatalf("failed to open pty: %v", err)
+		}
+		defer assert.Close(t, ptmx)
+		defer assert.Close(t, tty)
+
+		_, err = ptmx.Write([]byte("\x1b[Bj\x1b[A \r"))
+		assert.Success(t, err)
+
+		out := &bytes.Buffer{}
+		sel, err := xterm.MultiSelect(env, tty, out, "Pick colors:", []string{"red", "green", "blue"}, []int{0})
+		assert.Success(t, err)
+		assert.StringJSON(t, `[
+  0,
+  1
+]`, sel)
+	})
+}
@@ -0,0 +1,72 @@
+package xterm
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"golang.org/x/term"
+)
+
+// ErrNotTTY is returned when a terminal operation is attempted on a non TTY.
+var ErrNotTTY = errors.New("not a terminal")
+
+// WinSize is the size of a terminal in columns and rows.
+type WinSize struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Size returns the size of the terminal w.
+// ErrNotTTY is returned if w is not a TTY.
+func Size(w io.Writer) (WinSize, error) {
+	fd, ok := ttyFd(w)
+	if !ok {
+		return WinSize{}, ErrNotTTY
+	}
+	width, height, err := term.GetSize(fd)
+	if err != nil {
+		return WinSize{}, err
+	}
+	return WinSize{Width: width, Height: height}, nil
+}
+
+// NotifySize sends the size of the terminal w on the returned channel immediately
+// and then again every time it changes. The channel is closed once ctx is done.
+//
+// Changes are detected with SIGWINCH on unix and by polling on windows.
+// ErrNotTTY is returned if w is not a TTY.
+func NotifySize(ctx context.Context, w io.Writer) (<-chan WinSize, error) {
+	size, err := Size(w)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan WinSize, 1)
+	ch <- size
+	resized, stop := notifyResize()
+	go func() {
+		defer close(ch)
+		defer stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-resized:
+			}
+
+			size2, err := Size(w)
+			if err != nil || size2 == size {
+				continue
+			}
+			size = size2
+			select {
+			case <-ctx.Done():
+				return
+			case ch <- size:
+			}
+		}
+	}()
+	return ch, nil
+}
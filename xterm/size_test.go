@@ -0,0 +1,55 @@
+//go:build !windows
+
+package xterm_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/creack/pty"
+
+	"oss.terrastruct.com/util-go/assert"
+	"oss.terrastruct.com/util-go/xterm"
+)
+
+func TestSize(t *testing.T) {
+	t.Parallel()
+
+	_, err := xterm.Size(&bytes.Buffer{})
+	if !errors.Is(err, xterm.ErrNotTTY) {
+		t.Fatalf("expected ErrNotTTY: %v", err)
+	}
+
+	ptmx, tty, err := pty.Open()
+	if err != nil {
+		t.Fatalf("failed to open pty: %v", err)
+	}
+	defer assert.Close(t, ptmx)
+	defer assert.Close(t, tty)
+
+	err = pty.Setsize(ptmx, &pty.Winsize{Cols: 80, Rows: 24})
+	assert.Success(t, err)
+	size, err := xterm.Size(tty)
+	assert.Success(t, err)
+	assert.Equal(t, xterm.WinSize{Width: 80, Height: 24}, size)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sizes, err := xterm.NotifySize(ctx, tty)
+	assert.Success(t, err)
+	assert.Equal(t, size, <-sizes)
+
+	err = pty.Setsize(ptmx, &pty.Winsize{Cols: 120, Rows: 40})
+	assert.Success(t, err)
+	// tty is not our controlling terminal so the kernel does not send us SIGWINCH.
+	err = syscall.Kill(syscall.Getpid(), syscall.SIGWINCH)
+	assert.Success(t, err)
+	assert.Equal(t, xterm.WinSize{Width: 120, Height: 40}, <-sizes)
+
+	cancel()
+	for range sizes {
+	}
+}
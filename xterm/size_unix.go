@@ -0,0 +1,19 @@
+//go:build !windows
+
+package xterm
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyResize returns a channel that receives whenever the terminal may have
+// been resized and a function to stop notifications.
+func notifyResize() (<-chan os.Signal, func()) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGWINCH)
+	return sigs, func() {
+		signal.Stop(sigs)
+	}
+}
@@ -0,0 +1,16 @@
+//go:build windows
+
+package xterm
+
+import (
+	"time"
+)
+
+// notifyResize returns a channel that receives whenever the terminal may have
+// been resized and a function to stop notifications.
+//
+// windows has no SIGWINCH so we poll.
+func notifyResize() (<-chan time.Time, func()) {
+	t := time.NewTicker(time.Millisecond * 250)
+	return t.C, t.Stop
+}
@@ -0,0 +1,118 @@
+package xterm
+
+import (
+	"io"
+	"unicode"
+
+	"golang.org/x/text/width"
+)
+
+// Strip removes all CSI and OSC escape sequences from s.
+// Use it to get the plain text of output from Tput and Style.Render.
+func Strip(s string) string {
+	var sp stripper
+	return string(sp.strip([]byte(s)))
+}
+
+// NewStripWriter returns a writer that removes all CSI and OSC escape sequences
+// from what is written before writing to w. Sequences may be split across writes.
+func NewStripWriter(w io.Writer) io.Writer {
+	return &stripWriter{w: w}
+}
+
+type stripWriter struct {
+	w  io.Writer
+	sp stripper
+}
+
+func (sw *stripWriter) Write(p []byte) (int, error) {
+	_, err := sw.w.Write(sw.sp.strip(p))
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+type stripState int
+
+const (
+	stateText stripState = iota
+	stateEsc
+	stateCSI
+	stateOSC
+	stateOSCEsc
+)
+
+// stripper is a state machine that removes escape sequences from a stream.
+//
+// See https://invisible-island.net/xterm/ctlseqs/ctlseqs.html
+type stripper struct {
+	state stripState
+}
+
+func (sp *stripper) strip(p []byte) []byte {
+	out := make([]byte, 0, len(p))
+	for _, b := range p {
+		switch sp.state {
+		case stateText:
+			if b == 0x1b {
+				sp.state = stateEsc
+				continue
+			}
+			out = append(out, b)
+		case stateEsc:
+			switch b {
+			case '[':
+				sp.state = stateCSI
+			case ']':
+				sp.state = stateOSC
+			default:
+				// Two byte escape sequence.
+				sp.state = stateText
+			}
+		case stateCSI:
+			// Parameter and intermediate bytes until the final byte.
+			if 0x40 <= b && b <= 0x7e {
+				sp.state = stateText
+			}
+		case stateOSC:
+			switch b {
+			case 0x07:
+				sp.state = stateText
+			case 0x1b:
+				sp.state = stateOSCEsc
+			}
+		case stateOSCEsc:
+			if b == '\\' {
+				sp.state = stateText
+			} else {
+				sp.state = stateOSC
+			}
+		}
+	}
+	return out
+}
+
+// VisibleWidth returns the number of columns s occupies in a terminal.
+//
+// Escape sequences and control characters take no columns, combining marks take
+// none and East Asian wide and fullwidth runes take two.
+func VisibleWidth(s string) int {
+	n := 0
+	for _, r := range Strip(s) {
+		n += RuneWidth(r)
+	}
+	return n
+}
+
+// RuneWidth returns the number of columns r occupies in a terminal.
+func RuneWidth(r rune) int {
+	if unicode.IsControl(r) || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
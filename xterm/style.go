@@ -0,0 +1,109 @@
+package xterm
+
+import (
+	"io"
+	"strings"
+
+	"oss.terrastruct.com/util-go/xos"
+)
+
+// Style is a composable set of text attributes.
+//
+// Use Render to apply it to text or Caps to use it with Tput, Prefix
+// and cmdlog.Logger.WithPrefix.
+type Style struct {
+	// Fg and Bg are color caps like Red, Color256(n) or RGB(r, g, b).
+	// Bg also accepts foreground caps and converts them.
+	Fg string
+	Bg string
+
+	Bold      bool
+	Dim       bool
+	Italic    bool
+	Underline bool
+
+	// Hyperlink is a URL the text links to. See Hyperlink.
+	Hyperlink string
+}
+
+// With returns s with the set fields of s2 applied on top.
+func (s Style) With(s2 Style) Style {
+	if s2.Fg != "" {
+		s.Fg = s2.Fg
+	}
+	if s2.Bg != "" {
+		s.Bg = s2.Bg
+	}
+	s.Bold = s.Bold || s2.Bold
+	s.Dim = s.Dim || s2.Dim
+	s.Italic = s.Italic || s2.Italic
+	s.Underline = s.Underline || s2.Underline
+	if s2.Hyperlink != "" {
+		s.Hyperlink = s2.Hyperlink
+	}
+	return s
+}
+
+// Caps returns the escape sequences for all attributes of s but Hyperlink.
+func (s Style) Caps() string {
+	var sb strings.Builder
+	if s.Bold {
+		sb.WriteString(Bold)
+	}
+	if s.Dim {
+		sb.WriteString(Dim)
+	}
+	if s.Italic {
+		sb.WriteString(Italic)
+	}
+	if s.Underline {
+		sb.WriteString(Underline)
+	}
+	sb.WriteString(s.Fg)
+	sb.WriteString(toBg(s.Bg))
+	return sb.String()
+}
+
+// Render returns str styled with s if ShouldColor.
+// The hyperlink is added if ShouldHyperlink.
+//
+// str may contain spans rendered with another Style or Tput. The attributes of s are
+// restored after each span ends so nested styles do not clobber outer ones.
+func (s Style) Render(env *xos.Env, w io.Writer, str string) string {
+	if d := ColorDepth(env, w); d != DepthNone {
+		str = nest(Downgrade(s.Caps(), d), str)
+	}
+	if s.Hyperlink != "" {
+		str = Hyperlink(env, w, s.Hyperlink, str)
+	}
+	return str
+}
+
+// nest wraps s with caps and reapplies caps after every reset in s.
+func nest(caps, s string) string {
+	if caps == "" {
+		return s
+	}
+	return caps + strings.ReplaceAll(s, reset, reset+caps) + reset
+}
+
+// toBg converts foreground color caps to their background equivalent.
+func toBg(caps string) string {
+	return sgrRegex.ReplaceAllStringFunc(caps, func(seq string) string {
+		params := strings.Split(sgrRegex.FindStringSubmatch(seq)[1], ";")
+		if len(params) == 0 {
+			return seq
+		}
+		switch p := params[0]; {
+		case p == "38":
+			params[0] = "48"
+		case len(p) == 2 && (p[0] == '3' || p[0] == '9') && p[1] >= '0' && p[1] <= '7':
+			if p[0] == '3' {
+				params[0] = "4" + p[1:]
+			} else {
+				params[0] = "10" + p[1:]
+			}
+		}
+		return csi + strings.Join(params, ";") + "m"
+	})
+}
@@ -0,0 +1,329 @@
+package xterm
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+
+	"oss.terrastruct.com/util-go/xos"
+)
+
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignRight
+)
+
+type PlainFormat int
+
+const (
+	TSV PlainFormat = iota
+	CSV
+)
+
+// minColumnWidth is the narrowest a column is shrunk to fit the terminal width.
+const minColumnWidth = 4
+
+// Table renders rows of cells aligned into columns.
+//
+// When w is not a terminal and $COLOR does not force color, the table is written
+// in a plain TSV or CSV format instead for consumption by other programs.
+type Table struct {
+	env     *xos.Env
+	w       io.Writer
+	headers []string
+	rows    [][]string
+
+	// Border draws borders around all cells.
+	Border bool
+	// Align is the alignment of each column. Columns default to AlignLeft.
+	Align []Align
+	// HeaderStyle is applied to the header cells. Defaults to bold.
+	HeaderStyle Style
+	// Wrap wraps cells wider than their column instead of truncating them.
+	Wrap bool
+	// Width is the maximum width of the table. If 0 and w is a TTY, the width of the
+	// terminal is used. Otherwise the table may be as wide as necessary.
+	Width int
+	// Plain is the format used when w is not a terminal. Defaults to TSV.
+	Plain PlainFormat
+}
+
+// NewTable returns a table to be rendered to w. headers may be empty.
+func NewTable(env *xos.Env, w io.Writer, headers ...string) *Table {
+	return &Table{
+		env:         env,
+		w:           w,
+		headers:     headers,
+		HeaderStyle: Style{Bold: true},
+	}
+}
+
+// Append adds a row of cells. Cells may contain escape sequences and newlines.
+func (t *Table) Append(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+// Render writes the table to w.
+func (t *Table) Render() error {
+	if !ShouldColor(t.env, t.w) && !isTTY(t.w) {
+		return t.renderPlain()
+	}
+
+	widths := t.columnWidths()
+
+	var sb strings.Builder
+	if t.Border {
+		t.writeBorder(&sb, widths, "┌", "┬", "┐")
+	}
+	if len(t.headers) > 0 {
+		headers := make([]string, len(t.headers))
+		for i, h := range t.headers {
+			headers[i] = t.HeaderStyle.Render(t.env, t.w, h)
+		}
+		t.writeRow(&sb, widths, headers)
+		if t.Border {
+			t.writeBorder(&sb, widths, "├", "┼", "┤")
+		}
+	}
+	for _, row := range t.rows {
+		t.writeRow(&sb, widths, row)
+	}
+	if t.Border {
+		t.writeBorder(&sb, widths, "└", "┴", "┘")
+	}
+
+	_, err := io.WriteString(t.w, sb.String())
+	return err
+}
+
+func (t *Table) renderPlain() error {
+	rows := t.rows
+	if len(t.headers) > 0 {
+		rows = append([][]string{t.headers}, rows...)
+	}
+
+	if t.Plain == CSV {
+		cw := csv.NewWriter(t.w)
+		for _, row := range rows {
+			plain := make([]string, len(row))
+			for i, cell := range row {
+				plain[i] = Strip(cell)
+			}
+			err := cw.Write(plain)
+			if err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	var sb strings.Builder
+	r := strings.NewReplacer("\t", " ", "\n", " ")
+	for _, row := range rows {
+		for i, cell := range row {
+			if i > 0 {
+				sb.WriteByte('\t')
+			}
+			sb.WriteString(r.Replace(Strip(cell)))
+		}
+		sb.WriteByte('\n')
+	}
+	_, err := io.WriteString(t.w, sb.String())
+	return err
+}
+
+func (t *Table) numColumns() int {
+	n := len(t.headers)
+	for _, row := range t.rows {
+		if len(row) > n {
+			n = len(row)
+		}
+	}
+	return n
+}
+
+// columnWidths returns the width of each column shrunk to fit within the maximum
+// width of the table.
+func (t *Table) columnWidths() []int {
+	widths := make([]int, t.numColumns())
+	measure := func(row []string) {
+		for i, cell := range row {
+			for _, line := range strings.Split(cell, "\n") {
+				if w := VisibleWidth(line); w > widths[i] {
+					widths[i] = w
+				}
+			}
+		}
+	}
+	measure(t.headers)
+	for _, row := range t.rows {
+		measure(row)
+	}
+
+	maxWidth := t.Width
+	if maxWidth == 0 {
+		size, _ := Size(t.w)
+		maxWidth = size.Width
+	}
+	if maxWidth <= 0 {
+		return widths
+	}
+
+	// Shrink the widest column one at a time until the table fits.
+	for t.tableWidth(widths) > maxWidth {
+		widest := 0
+		for i, w := range widths {
+			if w > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= minColumnWidth {
+			break
+		}
+		widths[widest]--
+	}
+	return widths
+}
+
+func (t *Table) tableWidth(widths []int) int {
+	n := 0
+	for _, w := range widths {
+		n += w
+	}
+	if t.Border {
+		return n + 3*len(widths) + 1
+	}
+	return n + 2*(len(widths)-1)
+}
+
+func (t *Table) writeBorder(sb *strings.Builder, widths []int, left, mid, right string) {
+	sb.WriteString(left)
+	for i, w := range widths {
+		if i > 0 {
+			sb.WriteString(mid)
+		}
+		sb.WriteString(strings.Repeat("─", w+2))
+	}
+	sb.WriteString(right + "\n")
+}
+
+func (t *Table) writeRow(sb *strings.Builder, widths []int, row []string) {
+	// Each cell is split into the lines it occupies.
+	cells := make([][]string, len(widths))
+	height := 1
+	for i, w := range widths {
+		cell := ""
+		if i < len(row) {
+			cell = row[i]
+		}
+		for _, line := range strings.Split(cell, "\n") {
+			if t.Wrap {
+				cells[i] = append(cells[i], wrapWidth(line, w)...)
+			} else {
+				cells[i] = append(cells[i], Truncate(line, w))
+			}
+		}
+		if len(cells[i]) > height {
+			height = len(cells[i])
+		}
+	}
+
+	for j := 0; j < height; j++ {
+		if t.Border {
+			sb.WriteString("│ ")
+		}
+		for i, w := range widths {
+			if i > 0 {
+				if t.Border {
+					sb.WriteString(" │ ")
+				} else {
+					sb.WriteString("  ")
+				}
+			}
+			line := ""
+			if j < len(cells[i]) {
+				line = cells[i][j]
+			}
+			last := i == len(widths)-1 && !t.Border
+			sb.WriteString(t.pad(i, line, w, last))
+		}
+		if t.Border {
+			sb.WriteString(" │")
+		}
+		sb.WriteByte('\n')
+	}
+}
+
+// pad aligns s within w columns. Trailing padding is omitted from the last column
+// of a table without borders.
+func (t *Table) pad(col int, s string, w int, last bool) string {
+	padding := strings.Repeat(" ", w-VisibleWidth(s))
+	if col < len(t.Align) && t.Align[col] == AlignRight {
+		return padding + s
+	}
+	if last {
+		return s
+	}
+	return s + padding
+}
+
+// Truncate shortens s to w columns ending it with an ellipsis.
+// Escape sequences are removed from s if it must be truncated.
+func Truncate(s string, w int) string {
+	if VisibleWidth(s) <= w {
+		return s
+	}
+	var sb strings.Builder
+	n := 0
+	for _, r := range Strip(s) {
+		rw := RuneWidth(r)
+		if n+rw > w-1 {
+			break
+		}
+		sb.WriteRune(r)
+		n += rw
+	}
+	return sb.String() + "…"
+}
+
+// wrapWidth breaks s into lines of at most w columns on spaces if possible.
+// Escape sequences are removed from s if it must be wrapped.
+func wrapWidth(s string, w int) []string {
+	if VisibleWidth(s) <= w {
+		return []string{s}
+	}
+
+	var lines []string
+	var line []rune
+	lineWidth := 0
+	for _, word := range strings.Fields(Strip(s)) {
+		wordWidth := VisibleWidth(word)
+		if lineWidth > 0 && lineWidth+1+wordWidth <= w {
+			line = append(line, ' ')
+			line = append(line, []rune(word)...)
+			lineWidth += 1 + wordWidth
+			continue
+		}
+		if lineWidth > 0 {
+			lines = append(lines, string(line))
+			line, lineWidth = nil, 0
+		}
+		// Hard break words wider than w.
+		for _, r := range word {
+			rw := RuneWidth(r)
+			if lineWidth+rw > w {
+				lines = append(lines, string(line))
+				line, lineWidth = nil, 0
+			}
+			line = append(line, r)
+			lineWidth += rw
+		}
+	}
+	if lineWidth > 0 {
+		lines = append(lines, string(line))
+	}
+	return lines
+}
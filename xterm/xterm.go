@@ -15,9 +15,14 @@ import (
 // See https://invisible-island.net/xterm/ctlseqs/ctlseqs.html#h3-Functions-using-CSI-_-ordered-by-the-final-character_s_
 const (
 	csi   = "\x1b["
+	osc   = "\x1b]"
+	st    = "\x1b\\"
 	reset = csi + "0m"
 
-	Bold = csi + "1m"
+	Bold      = csi + "1m"
+	Dim       = csi + "2m"
+	Italic    = csi + "3m"
+	Underline = csi + "4m"
 
 	Red     = csi + "31m"
 	Green   = csi + "32m"
@@ -32,6 +37,9 @@ const (
 	BrightBlue    = csi + "94m"
 	BrightMagenta = csi + "95m"
 	BrightCyan    = csi + "96m"
+
+	EraseLine = csi + "2K"
+	CursorUp  = csi + "1A"
 )
 
 var colors = [...]string{
@@ -50,15 +58,32 @@ var colors = [...]string{
 	BrightCyan,
 }
 
-// isTTY checks whether the given writer is a *os.File TTY.
-func isTTY(w io.Writer) bool {
-	f, ok := w.(interface {
+// isTTY checks whether the given reader or writer is a *os.File TTY.
+func isTTY(rw interface{}) bool {
+	_, ok := ttyFd(rw)
+	return ok
+}
+
+// ttyFd returns the file descriptor of rw if it is a TTY.
+func ttyFd(rw interface{}) (int, bool) {
+	f, ok := rw.(interface {
 		Fd() uintptr
 	})
-	return ok && term.IsTerminal(int(f.Fd()))
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return 0, false
+	}
+	return int(f.Fd()), true
 }
 
-func shouldColor(env *xos.Env, w io.Writer) bool {
+// ShouldColor reports whether escape sequences should be written to w.
+//
+// $COLOR is obeyed to force enable/disable and $TERM=dumb disables.
+// Otherwise escape sequences are written only if w is a TTY.
+func ShouldColor(env *xos.Env, w io.Writer) bool {
+	switch env.Getenv("COLOR") {
+	case "256", "truecolor":
+		return true
+	}
 	eb, err := env.Bool("COLOR")
 	if eb != nil {
 		return *eb
@@ -72,14 +97,18 @@ func shouldColor(env *xos.Env, w io.Writer) bool {
 	return isTTY(w)
 }
 
+// Tput returns s formatted with caps if ShouldColor.
+//
+// Spans of s already formatted with Tput or Style.Render are preserved.
 func Tput(env *xos.Env, w io.Writer, caps, s string) string {
 	if caps == "" {
 		return s
 	}
-	if !shouldColor(env, w) {
+	d := ColorDepth(env, w)
+	if d == DepthNone {
 		return s
 	}
-	return caps + s + reset
+	return nest(Downgrade(caps, d), s)
 }
 
 func Prefix(env *xos.Env, w io.Writer, caps, s string) string {
@@ -90,10 +119,20 @@ func Prefix(env *xos.Env, w io.Writer, caps, s string) string {
 var crc64Table = crc64.MakeTable(crc64.ISO)
 
 // CC meaning constant color. So constant color prefix.
+//
+// The color is picked from a larger palette when w supports 256 colors or truecolor.
 func CCPrefix(env *xos.Env, w io.Writer, s string) string {
 	sum := crc64.Checksum([]byte(s), crc64Table)
 	rand := rand.New(rand.NewSource(int64(sum)))
 
-	color := colors[rand.Intn(len(colors))]
+	var color string
+	switch ColorDepth(env, w) {
+	case DepthTrue:
+		color = colorsTrue[rand.Intn(len(colorsTrue))]
+	case Depth256:
+		color = colors256[rand.Intn(len(colors256))]
+	default:
+		color = colors[rand.Intn(len(colors))]
+	}
 	return Prefix(env, w, color, s)
 }
@@ -0,0 +1,237 @@
+package xterm_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"oss.terrastruct.com/util-go/assert"
+	"oss.terrastruct.com/util-go/xos"
+	"oss.terrastruct.com/util-go/xterm"
+)
+
+func TestColorDepth(t *testing.T) {
+	t.Parallel()
+
+	tca := []struct {
+		name    string
+		environ []string
+		exp     xterm.Depth
+	}{
+		{
+			name: "none",
+			exp:  xterm.DepthNone,
+		},
+		{
+			name:    "COLOR=0",
+			environ: []string{"COLOR=0", "COLORTERM=truecolor"},
+			exp:     xterm.DepthNone,
+		},
+		{
+			name:    "COLOR=1",
+			environ: []string{"COLOR=1"},
+			exp:     xterm.Depth16,
+		},
+		{
+			name:    "TERM",
+			environ: []string{"COLOR=1", "TERM=xterm-256color"},
+			exp:     xterm.Depth256,
+		},
+		{
+			name:    "COLORTERM",
+			environ: []string{"COLOR=1", "TERM=xterm-256color", "COLORTERM=24bit"},
+			exp:     xterm.DepthTrue,
+		},
+		{
+			name:    "COLOR=256",
+			environ: []string{"COLOR=256", "COLORTERM=truecolor"},
+			exp:     xterm.Depth256,
+		},
+		{
+			name:    "COLOR=truecolor",
+			environ: []string{"COLOR=truecolor", "TERM=dumb"},
+			exp:     xterm.DepthTrue,
+		},
+	}
+
+	for _, tc := range tca {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			env := xos.NewEnv(tc.environ)
+			assert.Equal(t, tc.exp, xterm.ColorDepth(env, &bytes.Buffer{}))
+		})
+	}
+}
+
+func TestDowngrade(t *testing.T) {
+	t.Parallel()
+
+	caps := xterm.Bold + xterm.RGB(255, 0, 0) + xterm.BgColor256(21)
+	assert.Equal(t, caps, xterm.Downgrade(caps, xterm.DepthTrue))
+	assert.Equal(t, "\x1b[1m\x1b[38;5;196m\x1b[48;5;21m", xterm.Downgrade(caps, xterm.Depth256))
+	assert.Equal(t, "\x1b[1m\x1b[91m\x1b[44m", xterm.Downgrade(caps, xterm.Depth16))
+	assert.Equal(t, xterm.Blue, xterm.Downgrade(xterm.Blue, xterm.Depth16))
+
+	env := xos.NewEnv([]string{"COLOR=1"})
+	assert.Equal(t, "\x1b[91mhi\x1b[0m", xterm.Tput(env, &bytes.Buffer{}, xterm.RGB(250, 10, 10), "hi"))
+}
+
+func TestCCPrefix(t *testing.T) {
+	t.Parallel()
+
+	env := xos.NewEnv([]string{"COLOR=1"})
+	assert.Equal(t, "\x1b[93mlochness\x1b[0m:", xterm.CCPrefix(env, &bytes.Buffer{}, "lochness"))
+
+	env.Setenv("COLOR", "256")
+	p := xterm.CCPrefix(env, &bytes.Buffer{}, "lochness")
+	assert.Equal(t, p, xterm.CCPrefix(env, &bytes.Buffer{}, "lochness"))
+	if !bytes.Contains([]byte(p), []byte("38;5;")) {
+		t.Fatalf("expected 256 color prefix: %q", p)
+	}
+}
+
+func TestStyle(t *testing.T) {
+	t.Parallel()
+
+	env := xos.NewEnv([]string{"COLOR=1"})
+	w := &bytes.Buffer{}
+
+	bold := xterm.Style{Bold: true}
+	s := bold.With(xterm.Style{Fg: xterm.Red, Bg: xterm.Blue, Underline: true})
+	assert.Equal(t, "\x1b[1m\x1b[4m\x1b[31m\x1b[44m", s.Caps())
+
+	inner := xterm.Style{Fg: xterm.Green}.Render(env, w, "inner")
+	got := bold.Render(env, w, "outer "+inner+" outer")
+	assert.Equal(t, "\x1b[1mouter \x1b[32minner\x1b[0m\x1b[1m outer\x1b[0m", got)
+
+	link := xterm.Style{Hyperlink: "https://d2lang.com"}.Render(env, w, "d2")
+	assert.Equal(t, "\x1b]8;;https://d2lang.com\x1b\\d2\x1b]8;;\x1b\\", link)
+
+	env.Setenv("COLOR", "0")
+	assert.Equal(t, "plain", s.Render(env, w, "plain"))
+}
+
+func TestStrip(t *testing.T) {
+	t.Parallel()
+
+	env := xos.NewEnv([]string{"COLOR=1"})
+	s := xterm.Style{Bold: true, Fg: xterm.RGB(1, 2, 3), Hyperlink: "https://d2lang.com"}.Render(env, &bytes.Buffer{}, "d2 ダイアグラム")
+	assert.Equal(t, "d2 ダイアグラム", xterm.Strip(s))
+	assert.Equal(t, 15, xterm.VisibleWidth(s))
+	assert.Equal(t, 1, xterm.VisibleWidth("é"))
+
+	b := &bytes.Buffer{}
+	sw := xterm.NewStripWriter(b)
+	for i := 0; i < len(s); i += 3 {
+		end := i + 3
+		if end > len(s) {
+			end = len(s)
+		}
+		_, err := sw.Write([]byte(s[i:end]))
+		assert.Success(t, err)
+	}
+	assert.Equal(t, "d2 ダイアグラム", b.String())
+}
+
+func TestTable(t *testing.T) {
+	t.Parallel()
+
+	newTable := func(env *xos.Env, w io.Writer) *xterm.Table {
+		tbl := xterm.NewTable(env, w, "NAME", "SIZE", "DESCRIPTION")
+		tbl.HeaderStyle = xterm.Style{}
+		tbl.Align = []xterm.Align{xterm.AlignLeft, xterm.AlignRight}
+		tbl.Append("d2", "12", "A modern diagram scripting language that turns text to diagrams.")
+		tbl.Append("ダイアグラム", "3456", xterm.Tput(env, w, xterm.Green, "ok"))
+		return tbl
+	}
+
+	t.Run("plain", func(t *testing.T) {
+		b := &bytes.Buffer{}
+		err := newTable(xos.NewEnv(nil), b).Render()
+		assert.Success(t, err)
+		assert.String(t, `NAME	SIZE	DESCRIPTION
+d2	12	A modern diagram scripting language that turns text to diagrams.
+ダイアグラム	3456	ok
+`, b.String())
+
+		b.Reset()
+		tbl := newTable(xos.NewEnv(nil), b)
+		tbl.Plain = xterm.CSV
+		err = tbl.Render()
+		assert.Success(t, err)
+		assert.String(t, `NAME,SIZE,DESCRIPTION
+d2,12,A modern diagram scripting language that turns text to diagrams.
+ダイアグラム,3456,ok
+`, b.String())
+	})
+
+	t.Run("truncate", func(t *testing.T) {
+		b := &bytes.Buffer{}
+		env := xos.NewEnv([]string{"COLOR=1"})
+		tbl := newTable(env, b)
+		tbl.Width = 40
+		err := tbl.Render()
+		assert.Success(t, err)
+		assert.String(t, `NAME          SIZE  DESCRIPTION
+d2              12  A modern diagram sc…
+ダイアグラム  3456  `+"\x1b[32mok\x1b[0m"+`
+`, b.String())
+	})
+
+	t.Run("border", func(t *testing.T) {
+		b := &bytes.Buffer{}
+		env := xos.NewEnv([]string{"COLOR=1"})
+		tbl := newTable(env, b)
+		tbl.Width = 40
+		tbl.Border = true
+		tbl.Wrap = true
+		err := tbl.Render()
+		assert.Success(t, err)
+		assert.String(t, `┌──────────────┬──────┬────────────────┐
+│ NAME         │ SIZE │ DESCRIPTION    │
+├──────────────┼──────┼────────────────┤
+│ d2           │   12 │ A modern       │
+│              │      │ diagram        │
+│              │      │ scripting      │
+│              │      │ language that  │
+│              │      │ turns text to  │
+│              │      │ diagrams.      │
+│ ダイアグラム │ 3456 │ `+"\x1b[32mok\x1b[0m"+`             │
+└──────────────┴──────┴────────────────┘
+`, b.String())
+	})
+}
+
+func TestHyperlink(t *testing.T) {
+	t.Parallel()
+
+	env := xos.NewEnv(nil)
+	w := &bytes.Buffer{}
+	assert.Equal(t, "d2", xterm.Hyperlink(env, w, "https://d2lang.com", "d2"))
+
+	env.Setenv("COLOR", "1")
+	assert.Equal(t, "\x1b]8;;https://d2lang.com\x1b\\d2\x1b]8;;\x1b\\", xterm.Hyperlink(env, w, "https://d2lang.com", "d2"))
+	assert.Equal(t, "d2", xterm.Strip(xterm.FileHyperlink(env, w, "x.d2", "d2")))
+
+	err := xterm.SetTitle(env, w, "d2")
+	assert.Success(t, err)
+	assert.Equal(t, "\x1b]2;d2\x1b\\", w.String())
+
+	// Control characters cannot end the sequence early.
+	assert.Equal(t, "\x1b]8;;https://d2lang.com/]2;pwned\x1b\\d2\x1b]8;;\x1b\\", xterm.Hyperlink(env, w, "https://d2lang.com/\x1b]2;pwned\a", "d2"))
+	w.Reset()
+	err = xterm.SetTitle(env, w, "d2\x07\x1b[2Jx")
+	assert.Success(t, err)
+	assert.Equal(t, "\x1b]2;d2[2Jx\x1b\\", w.String())
+
+	env.Setenv("HYPERLINKS", "0")
+	assert.Equal(t, "d2", xterm.FileHyperlink(env, w, "x.d2", "d2"))
+
+	u := xterm.FileURL("/tmp/x.d2")
+	if !strings.HasPrefix(u, "file://") || !strings.HasSuffix(u, "/tmp/x.d2") {
+		t.Fatalf("unexpected file URL: %q", u)
+	}
+}